@@ -1,47 +1,183 @@
 package goapi
 
 import (
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
 	"google.golang.org/protobuf/runtime/protoiface"
 )
 
 var (
-	comments = make(map[protoiface.MessageV1]string)
+	comments = make(map[protoiface.MessageV1]*comment)
 )
 
+// comment holds the comments of a proto element, as protoc reports them:
+// every line keeps the text after the comment marker, leading space included.
+type comment struct {
+	leading  string   // the comment right above the element
+	trailing string   // the comment after it, on the same or the next line
+	detached []string // the comments above the leading one, separated by blank lines
+}
+
 func initComment(req *plugin.CodeGeneratorRequest) {
 	for _, f := range req.GetProtoFile() {
 		for _, loc := range f.GetSourceCodeInfo().GetLocation() {
-			if loc.LeadingComments == nil {
+			if loc.LeadingComments == nil && loc.TrailingComments == nil && len(loc.LeadingDetachedComments) == 0 {
 				continue
 			}
-
-			// p is an array with format [f1, i1, f2, i2, ...]
-			// - f1 refers to the protobuf field tag
-			// - if field refer to by f1 is a slice, i1 refers to an element in that slice
-			// - f2 and i2 works recursively.
-			// So, [6, x] refers to the xth service defined in the file,
-			// since the field tag of Service is 6.
-			// [6, x, 2, y] refers to the yth method in that service,
-			// since the field tag of Method is 2.
-			p := loc.Path
-			switch {
-			case len(p) == 2 && p[0] == 6:
-				comments[f.Service[p[1]]] = *loc.LeadingComments
-			case len(p) == 4 && p[0] == 6 && p[2] == 2:
-				comments[f.Service[p[1]].Method[p[3]]] = *loc.LeadingComments
+			if d := locate(f, loc.Path); d != nil {
+				comments[d] = &comment{
+					leading:  loc.GetLeadingComments(),
+					trailing: loc.GetTrailingComments(),
+					detached: loc.GetLeadingDetachedComments(),
+				}
 			}
 		}
 	}
 }
 
+// locate reports the service, method, message, field, enum or enum value
+// of f at the source location path p, nil for anything else.
+//
+// p is an array with format [f1, i1, f2, i2, ...]
+//   - f1 refers to the protobuf field tag
+//   - if field refer to by f1 is a slice, i1 refers to an element in that slice
+//   - f2 and i2 works recursively.
+//
+// So, [6, x] refers to the xth service defined in the file,
+// since the field tag of Service is 6.
+// [6, x, 2, y] refers to the yth method in that service,
+// since the field tag of Method is 2.
+func locate(f *descriptor.FileDescriptorProto, p []int32) protoiface.MessageV1 {
+	if len(p) < 2 {
+		return nil
+	}
+	switch p[0] {
+	case 6: // service
+		serv := f.GetService()[p[1]]
+		switch {
+		case len(p) == 2:
+			return serv
+		case len(p) == 4 && p[2] == 2:
+			return serv.GetMethod()[p[3]]
+		}
+	case 4: // message_type
+		return locateInMessage(f.GetMessageType()[p[1]], p[2:])
+	case 5: // enum_type
+		return locateInEnum(f.GetEnumType()[p[1]], p[2:])
+	}
+	return nil
+}
+
+// locateInMessage is locate for the path p relative to the message m.
+func locateInMessage(m *descriptor.DescriptorProto, p []int32) protoiface.MessageV1 {
+	if len(p) == 0 {
+		return m
+	}
+	if len(p) < 2 {
+		return nil
+	}
+	switch p[0] {
+	case 2: // field
+		if len(p) == 2 {
+			return m.GetField()[p[1]]
+		}
+	case 3: // nested_type
+		return locateInMessage(m.GetNestedType()[p[1]], p[2:])
+	case 4: // enum_type
+		return locateInEnum(m.GetEnumType()[p[1]], p[2:])
+	}
+	return nil
+}
+
+// locateInEnum is locate for the path p relative to the enum e.
+func locateInEnum(e *descriptor.EnumDescriptorProto, p []int32) protoiface.MessageV1 {
+	switch {
+	case len(p) == 0:
+		return e
+	case len(p) == 2 && p[0] == 2: // value
+		return e.GetValue()[p[1]]
+	}
+	return nil
+}
+
+// getComment reports the comment documenting m: its leading comment, else
+// its trailing one, as in "string name = 1; // The name."
 func getComment(m protoiface.MessageV1) string {
 	c, ok := comments[m]
 	if !ok {
 		return ""
 	}
-	if c[len(c)-1] == '\n' {
-		return c[:len(c)-1]
+	if c.leading != "" {
+		return strings.TrimSuffix(c.leading, "\n")
+	}
+	return strings.TrimSuffix(c.trailing, "\n")
+}
+
+// getDetachedComments reports the comments above the one documenting m,
+// such as section headers, in source order.
+func getDetachedComments(m protoiface.MessageV1) []string {
+	c, ok := comments[m]
+	if !ok {
+		return nil
+	}
+	return c.detached
+}
+
+// getDescription reports the comment of m as plain text, for the documents
+// describing the API.
+func getDescription(m protoiface.MessageV1) string {
+	return plainComment(getComment(m))
+}
+
+// plainComment strips the space following the comment markers from every
+// line of c, deeper indentation such as code blocks is kept.
+func plainComment(c string) string {
+	lines := strings.Split(c, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(strings.TrimPrefix(l, " "), " \t")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// goDoc reports the Go doc comment of the declaration name, documented by
+// the comment c, after the detached comments above it. Every line starts
+// with "//", and the text with name as Go doc comments do.
+func goDoc(name, c string, detached []string) string {
+	var b strings.Builder
+	for _, d := range detached {
+		if d := plainComment(d); d != "" {
+			b.WriteString(commentLines(d) + "\n\n")
+		}
+	}
+	text := plainComment(c)
+	if text != name && !strings.HasPrefix(text, name+" ") {
+		text = strings.TrimSpace(name + " " + text)
+	}
+	b.WriteString(commentLines(text))
+	return b.String()
+}
+
+// deprecatedDoc appends to the Go doc comment doc the paragraph
+// staticcheck and gopls flag the uses of deprecated declarations by.
+func deprecatedDoc(doc string) string {
+	const notice = "// Deprecated: Do not use."
+	if doc == "" {
+		return notice
+	}
+	return doc + "\n//\n" + notice
+}
+
+// commentLines reports text as // comment lines.
+func commentLines(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, l := range lines {
+		if l == "" {
+			lines[i] = "//"
+		} else {
+			lines[i] = "// " + l
+		}
 	}
-	return c
+	return strings.Join(lines, "\n")
 }
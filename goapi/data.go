@@ -1,6 +1,10 @@
 package goapi
 
-import "strings"
+import (
+	"strings"
+
+	"github.com/open-api-go/protoc-gen-go_api/pbinfo"
+)
 
 var (
 	fn = map[string]interface{}{
@@ -10,25 +14,82 @@ var (
 )
 
 type FileData struct {
-	Version   string         // 版本号
-	Source    string         // 源文件
-	GoPackage string         // Go包名
-	Services  []*ServiceData // 服务数据
+	Version   string              // 版本号
+	Source    string              // 源文件
+	GoPackage string              // Go包名
+	Imports   []pbinfo.ImportSpec // 引用到的其他Go包
+	Services  []*ServiceData      // 服务数据
+
+	RawResponse bool // 接口里是否导出返回原始响应的方法
+
+	ServerImports []pbinfo.ImportSpec // 服务端文件引用到的其他Go包
+	Backends      []*serverBackend    // 生成的服务端框架
+	FakeImports   []pbinfo.ImportSpec // fake文件引用到的其他Go包
+}
+
+// RuntimeData 每个Go包一份的公共代码
+type RuntimeData struct {
+	Version       string              // 版本号
+	GoPackage     string              // Go包名
+	Imports       []pbinfo.ImportSpec // 引用到的其他Go包
+	ErrTyp        string              // 错误响应body的类型名
+	Server        bool                // 是否生成服务端用到的公共代码
+	Backends      []*serverBackend    // 生成的服务端框架
+	Fake          bool                // 是否生成fake用到的公共代码
+	QueryStyle    string              // repeated字段的query参数格式
+	FormSeparator string              // form和multipart的key里嵌套字段名的连接符
 }
 
 type ServiceData struct {
-	PkgName  string        // package name
-	ServName string        // 服务名，不带Service的
-	Methods  []*MethodData // 方法数据
+	PkgName    string        // package name
+	ServName   string        // 服务名，不带Service的
+	BaseURL    string        // 客户端默认的请求地址，不以/结尾
+	Doc        string        // 客户端接口的Go文档注释，服务没有注释时为空
+	Deprecated bool          // 服务是否标记了deprecated
+	Methods    []*MethodData // 方法数据
+
+	MockMethods   []*MethodData // mock实现的接口方法，包括返回原始响应的方法
+	ServerMethods []*MethodData // 服务端接口的方法，不含流式方法
+	Routes        []*RouteData  // 服务端的路由
 }
 
 type MethodData struct {
-	ServName string // 所属服务名
-	MethName string // 方法名
-	Comment  string // 注释。只取头注释
-	ReqTyp   string // 请求类型名
-	ResTyp   string // 返回类型名
-	ReqCode  string // 请求代码
+	ServName   string // 所属服务名
+	MethName   string // 方法名
+	Comment    string // 注释。头注释，没有时取尾注释
+	Doc        string // 生成的Go文档注释，每行以//开头，前面是分离的注释
+	Deprecated bool   // 方法是否标记了deprecated，Doc里已经带上Deprecated段落
+	ReqTyp     string // 请求类型名
+	ResTyp     string // 返回类型名
+	RawName    string // 返回原始响应的方法名，raw_response时导出
+	Timeout    string // 默认超时的Go表达式，为空表示不设置
+	ReqCode    string // 请求代码
+	ResCode    string // 解析响应的代码
+}
+
+// RouteData 服务端的一个路由，对应HttpRule或者它的一个additional_bindings
+type RouteData struct {
+	MethName     string       // 处理请求的接口方法名
+	ReqTyp       string       // 请求类型名
+	Verb         string       // HTTP方法，大写
+	Path         string       // 路径模板
+	Prefix       string       // 路径的字面量前缀，注册到ServeMux上
+	Pattern      string       // 匹配转义后路径的正则，每个变量一个分组
+	Vars         []string     // 变量的字段路径，和正则的分组一一对应
+	VerbSuffix   bool         // 路径模板是否以:verb结尾
+	Segments     []string     // 给gin等路由用的路径段，变量是*或者**
+	Body         string       // HttpRule.body的字段路径，*表示整个请求
+	BodyFormat   string       // body格式，json、form或multi
+	ResponseBody string       // HttpRule.response_body的字段名
+	HTTPBody     bool         // 返回值是google.api.HttpBody，原样写出
+	Files        []*RouteFile // multipart body里的文件part
+}
+
+// RouteFile 服务端multipart body里的一个文件part
+type RouteFile struct {
+	Name             string // part名，即相对body的字段路径，用form_separator连接
+	FilenameField    string // 存文件名的字段路径，可以为空
+	ContentTypeField string // 存content type的字段路径，可以为空
 }
 
 var (
@@ -0,0 +1,318 @@
+package goapi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	goapipb "github.com/open-api-go/protoc-gen-go_api/goapi/annotations"
+	"google.golang.org/genproto/googleapis/api/annotations"
+)
+
+// docPage writes the Markdown reference of a service: its methods with
+// their HTTP rules and parameters, then the messages and enums they use.
+type docPage struct {
+	opts  *Options
+	fd    *descriptor.FileDescriptorProto
+	serv  *descriptor.ServiceDescriptorProto
+	b     strings.Builder
+	types []string        // messages and enums to describe, in order of use
+	seen  map[string]bool // types already in types
+}
+
+func newDocPage(fd *descriptor.FileDescriptorProto, serv *descriptor.ServiceDescriptorProto, opts *Options) *docPage {
+	return &docPage{opts: opts, fd: fd, serv: serv, seen: map[string]bool{}}
+}
+
+// render reports the page.
+func (p *docPage) render() (string, error) {
+	fmt.Fprintf(&p.b, "<!-- Code generated by protoc-gen-go_api(github.com/open-api-go/protoc-gen-go_api version=%s). DO NOT EDIT. -->\n", Release)
+	fmt.Fprintf(&p.b, "<!-- source: %s -->\n\n", p.fd.GetName())
+	fmt.Fprintf(&p.b, "# %s\n\n", p.serv.GetName())
+	if p.serv.GetOptions().GetDeprecated() {
+		p.b.WriteString("**Deprecated.**\n\n")
+	}
+	if c := getDescription(p.serv); c != "" {
+		p.b.WriteString(c + "\n\n")
+	}
+	fmt.Fprintf(&p.b, "Base URL: `%s`\n\n", baseURL(p.fd, p.serv))
+	errTyp := p.opts.ErrorType
+	if errTyp == "" {
+		errTyp = "." + statusValue
+	}
+	fmt.Fprintf(&p.b, "Errors are returned with a non-2xx status and a %s body.\n\n", p.typeLink(errTyp))
+
+	type method struct {
+		meth  *descriptor.MethodDescriptorProto
+		rules []*httpInfo
+	}
+	var meths []method
+	for _, meth := range p.serv.GetMethod() {
+		if rules, _ := methodRules(p.fd, p.serv, meth, p.opts); len(rules) > 0 {
+			meths = append(meths, method{meth, rules})
+		}
+	}
+	if len(meths) > 0 {
+		p.b.WriteString("## Methods\n\n")
+		for _, m := range meths {
+			fmt.Fprintf(&p.b, "- [%s](#%s)\n", m.meth.GetName(), mdAnchor(m.meth.GetName()))
+		}
+		p.b.WriteString("\n")
+	}
+	for _, m := range meths {
+		if err := p.method(m.meth, m.rules); err != nil {
+			return "", err
+		}
+	}
+	p.schemas()
+	return strings.TrimRight(p.b.String(), "\n") + "\n", nil
+}
+
+// method writes meth called through the HTTP rules.
+func (p *docPage) method(meth *descriptor.MethodDescriptorProto, rules []*httpInfo) error {
+	fmt.Fprintf(&p.b, "## %s\n\n", meth.GetName())
+	if meth.GetOptions().GetDeprecated() {
+		p.b.WriteString("**Deprecated.**\n\n")
+	}
+	if c := getDescription(meth); c != "" {
+		p.b.WriteString(c + "\n\n")
+	}
+	fmt.Fprintf(&p.b, "- Request: %s\n", p.typeLink(meth.GetInputType()))
+	fmt.Fprintf(&p.b, "- Response: %s\n", p.typeLink(meth.GetOutputType()))
+	if auth := authDescription(getMethodOptions(meth).GetAuth()); auth != "" {
+		fmt.Fprintf(&p.b, "- Auth: %s\n", auth)
+	}
+	p.b.WriteString("\n")
+
+	for _, info := range rules {
+		tmpl, err := parsePathTemplate(info.url)
+		if err != nil {
+			return fmt.Errorf("method %q: %v", meth.GetName(), err)
+		}
+		fmt.Fprintf(&p.b, "### `%s %s`\n\n", strings.ToUpper(info.verb), info.url)
+
+		var rows [][]string
+		for _, v := range tmpl.variables {
+			field := lookupField(meth.GetInputType(), v.fieldPath)
+			desc := p.fieldDescription(field)
+			if pat := tmpl.variablePattern(v); pat != "" {
+				desc = strings.TrimSpace(fmt.Sprintf("Matches `%s`. %s", pat, desc))
+			}
+			rows = append(rows, []string{"`" + v.fieldPath + "`", p.fieldType(field), desc})
+		}
+		p.table("Path parameters", rows)
+
+		rows = nil
+		query := queryParams(meth, info)
+		var names []string
+		for name := range query {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			field := query[name]
+			desc := p.fieldDescription(field)
+			if p.opts.QueryStyle == queryComma && field.GetLabel() == fieldLabelRepeated {
+				desc = strings.TrimSpace("Comma separated. " + desc)
+			}
+			rows = append(rows, []string{"`" + queryParamName(name, field, p.opts.QueryStyle) + "`", p.fieldType(field), desc})
+		}
+		p.table("Query parameters", rows)
+
+		if info.body != "" {
+			format := info.format
+			if format == "" {
+				format = p.opts.DefaultBody
+			}
+			if info.body == "*" {
+				fmt.Fprintf(&p.b, "Body (`%s`): the request, %s.\n\n", bodyContentType(format), p.typeLink(meth.GetInputType()))
+			} else {
+				field := lookupField(meth.GetInputType(), info.body)
+				fmt.Fprintf(&p.b, "Body (`%s`): the field `%s`, %s.\n\n", bodyContentType(format), info.body, p.fieldType(field))
+			}
+		}
+		switch {
+		case meth.GetOutputType() == httpBodyType:
+			p.b.WriteString("Response body: the raw content of the `google.api.HttpBody`.\n\n")
+		case info.responseBody != "":
+			field := lookupField(meth.GetOutputType(), info.responseBody)
+			fmt.Fprintf(&p.b, "Response body (`application/json`): the field `%s` of the response, %s.\n\n", info.responseBody, p.fieldType(field))
+		default:
+			fmt.Fprintf(&p.b, "Response body (`application/json`): %s.\n\n", p.typeLink(meth.GetOutputType()))
+		}
+	}
+	return nil
+}
+
+// authDescription reports how the (goapi.method).auth option auth
+// authenticates the requests, "" for none.
+func authDescription(auth *goapipb.Auth) string {
+	switch auth.GetScheme() {
+	case goapipb.Auth_SCHEME_BEARER:
+		return "bearer token in the `Authorization` header"
+	case goapipb.Auth_SCHEME_BASIC:
+		return "basic authentication in the `Authorization` header"
+	case goapipb.Auth_SCHEME_API_KEY:
+		if auth.GetInQuery() {
+			return fmt.Sprintf("API key in the query parameter `%s`", auth.GetName())
+		}
+		return fmt.Sprintf("API key in the `%s` header", auth.GetName())
+	}
+	return ""
+}
+
+// schemas writes the messages and enums the methods use, and the ones
+// these use in turn.
+func (p *docPage) schemas() {
+	if len(p.types) == 0 {
+		return
+	}
+	p.b.WriteString("## Types\n\n")
+	// Describing a message may add the types of its fields.
+	for i := 0; i < len(p.types); i++ {
+		name := p.types[i]
+		fmt.Fprintf(&p.b, "### %s\n\n", p.displayName(name))
+		switch t := descInfo.Type[name].(type) {
+		case *descriptor.DescriptorProto:
+			if c := getDescription(t); c != "" {
+				p.b.WriteString(c + "\n\n")
+			}
+			var rows [][]string
+			for _, field := range t.GetField() {
+				rows = append(rows, []string{"`" + field.GetJsonName() + "`", p.fieldType(field), p.fieldDescription(field)})
+			}
+			if len(rows) == 0 {
+				p.b.WriteString("No fields.\n\n")
+			}
+			p.table("", rows)
+		case *descriptor.EnumDescriptorProto:
+			if c := getDescription(t); c != "" {
+				p.b.WriteString(c + "\n\n")
+			}
+			var rows [][]string
+			for _, v := range t.GetValue() {
+				rows = append(rows, []string{"`" + v.GetName() + "`", fmt.Sprint(v.GetNumber()), getDescription(v)})
+			}
+			p.tableOf([]string{"Value", "Number", "Description"}, rows)
+		}
+	}
+}
+
+// table writes rows of name, type and description under the given title,
+// nothing if there are no rows.
+func (p *docPage) table(title string, rows [][]string) {
+	if len(rows) == 0 {
+		return
+	}
+	if title != "" {
+		fmt.Fprintf(&p.b, "%s:\n\n", title)
+	}
+	p.tableOf([]string{"Name", "Type", "Description"}, rows)
+}
+
+func (p *docPage) tableOf(header []string, rows [][]string) {
+	if len(rows) == 0 {
+		return
+	}
+	p.b.WriteString("| " + strings.Join(header, " | ") + " |\n")
+	p.b.WriteString("|" + strings.Repeat(" --- |", len(header)) + "\n")
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, c := range row {
+			cells[i] = mdCell(c)
+		}
+		p.b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+	p.b.WriteString("\n")
+}
+
+// fieldDescription reports the comment of field, led by its behaviors.
+func (p *docPage) fieldDescription(field *descriptor.FieldDescriptorProto) string {
+	var notes []string
+	if field.GetOptions().GetDeprecated() {
+		notes = append(notes, "**Deprecated.**")
+	}
+	for _, fb := range []struct {
+		b    annotations.FieldBehavior
+		note string
+	}{
+		{annotations.FieldBehavior_REQUIRED, "**Required.**"},
+		{annotations.FieldBehavior_OUTPUT_ONLY, "**Output only.**"},
+		{annotations.FieldBehavior_INPUT_ONLY, "**Input only.**"},
+		{annotations.FieldBehavior_IMMUTABLE, "**Immutable.**"},
+	} {
+		if hasFieldBehavior(field, fb.b) {
+			notes = append(notes, fb.note)
+		}
+	}
+	if c := getDescription(field); c != "" {
+		notes = append(notes, c)
+	}
+	return strings.Join(notes, " ")
+}
+
+// fieldType reports the proto type of field, messages and enums linking
+// to their description.
+func (p *docPage) fieldType(field *descriptor.FieldDescriptorProto) string {
+	if entry := mapEntry(field); entry != nil {
+		// Escaped, Markdown would take <...> for HTML.
+		return fmt.Sprintf("map&lt;%s, %s&gt;", p.fieldType(entry.GetField()[0]), p.fieldType(entry.GetField()[1]))
+	}
+	var t string
+	switch field.GetType() {
+	case fieldTypeMessage, descriptor.FieldDescriptorProto_TYPE_ENUM:
+		t = p.typeLink(field.GetTypeName())
+	default:
+		t = strings.ToLower(strings.TrimPrefix(field.GetType().String(), "TYPE_"))
+	}
+	if field.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED {
+		return "repeated " + t
+	}
+	return t
+}
+
+// typeLink reports a link to the description of the message or enum
+// typeName, adding it to the types described on the page. Types outside
+// the files passed to the plugin, such as the well-known types, are
+// only named.
+func (p *docPage) typeLink(typeName string) string {
+	if descInfo.Type[typeName] == nil || strings.HasPrefix(typeName, ".google.protobuf.") {
+		return "`" + strings.TrimPrefix(typeName, ".") + "`"
+	}
+	if !p.seen[typeName] {
+		p.seen[typeName] = true
+		p.types = append(p.types, typeName)
+	}
+	name := p.displayName(typeName)
+	return fmt.Sprintf("[%s](#%s)", name, mdAnchor(name))
+}
+
+// displayName reports typeName relative to the proto package of the page.
+func (p *docPage) displayName(typeName string) string {
+	name := strings.TrimPrefix(typeName, ".")
+	if pkg := p.fd.GetPackage(); pkg != "" && strings.HasPrefix(name, pkg+".") {
+		return strings.TrimPrefix(name, pkg+".")
+	}
+	return name
+}
+
+// mdAnchor reports the anchor GitHub gives to the heading s.
+func mdAnchor(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteByte('-')
+		}
+	}
+	return b.String()
+}
+
+// mdCell escapes s for a table cell, which must stay on one line.
+func mdCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
@@ -2,90 +2,430 @@ package goapi
 
 import (
 	"fmt"
+	"go/format"
+	"os"
+	"path"
 	"strings"
+	"time"
 
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+	goapipb "github.com/open-api-go/protoc-gen-go_api/goapi/annotations"
+	"github.com/open-api-go/protoc-gen-go_api/pbinfo"
+	"google.golang.org/genproto/googleapis/api/annotations"
 	"google.golang.org/protobuf/proto"
 )
 
 func Gen(req *plugin.CodeGeneratorRequest) (*plugin.CodeGeneratorResponse, error) {
-	initRest(req)
-	initComment(req)
-	opts, err := parseOptions(req.Parameter)
+	opts, err := parseOptions(req.GetParameter())
 	if err != nil {
 		return nil, err
 	}
-	_ = opts
+	initRest(req, opts)
+	initComment(req)
 
 	var resp plugin.CodeGeneratorResponse
+	// Go packages with services and the first file generated into each,
+	// every such package gets one runtime file.
+	var pkgs []string
+	pkgFile := map[string]*descriptor.FileDescriptorProto{}
+	// Files with services by Go package, for openapi_out=package.
+	pkgServFiles := map[string][]*descriptor.FileDescriptorProto{}
 	for _, f := range req.GetProtoFile() {
 		if !strContains(req.GetFileToGenerate(), f.GetName()) {
 			continue
 		}
-		data, err := parseRestFile(f)
+		data, err := parseRestFile(f, opts)
 		if err != nil {
 			return nil, err
 		}
+		if pkg, _ := descInfo.GoPackage(f); len(data.Services) > 0 {
+			if pkgFile[pkg.Path] == nil {
+				pkgs = append(pkgs, pkg.Path)
+				pkgFile[pkg.Path] = f
+			}
+			pkgServFiles[pkg.Path] = append(pkgServFiles[pkg.Path], f)
+		}
 		bs, err := getGoapiContent(data)
 		if err != nil {
 			return nil, err
 		}
-		resp.File = append(resp.File, &plugin.CodeGeneratorResponse_File{
-			Name:    proto.String(fmt.Sprintf("%s.api.go", strings.ReplaceAll(f.GetName(), ".proto", ""))),
-			Content: proto.String(bs),
-		})
+		name, err := outputFileName(f, ".api.go", opts)
+		if err != nil {
+			return nil, err
+		}
+		file, err := goFile(name, bs)
+		if err != nil {
+			return nil, err
+		}
+		resp.File = append(resp.File, file)
+
+		if opts.server() && len(data.Services) > 0 {
+			bs, err := getServerContent(data)
+			if err != nil {
+				return nil, err
+			}
+			file, err := goFile(strings.TrimSuffix(name, ".api.go")+".api_server.go", bs)
+			if err != nil {
+				return nil, err
+			}
+			resp.File = append(resp.File, file)
+		}
+		if opts.Mock && len(data.Services) > 0 {
+			bs, err := getMockContent(data)
+			if err != nil {
+				return nil, err
+			}
+			file, err := goFile(strings.TrimSuffix(name, ".api.go")+".api_mock.go", bs)
+			if err != nil {
+				return nil, err
+			}
+			resp.File = append(resp.File, file)
+		}
+		if opts.Fake && len(data.Services) > 0 {
+			bs, err := getFakeContent(data)
+			if err != nil {
+				return nil, err
+			}
+			file, err := goFile(strings.TrimSuffix(name, ".api.go")+".api_fake.go", bs)
+			if err != nil {
+				return nil, err
+			}
+			resp.File = append(resp.File, file)
+		}
+		if opts.OpenAPI != "" && opts.OpenAPIOut == openapiOutFile && len(data.Services) > 0 {
+			file, err := genOpenAPIFile([]*descriptor.FileDescriptorProto{f}, strings.TrimSuffix(name, ".api.go")+".openapi."+opts.OpenAPI, opts)
+			if err != nil {
+				return nil, err
+			}
+			resp.File = append(resp.File, file)
+		}
+		if opts.Docs == docsMarkdown {
+			for _, serv := range f.GetService() {
+				if opts.omitService(serv) {
+					continue
+				}
+				bs, err := newDocPage(f, serv, opts).render()
+				if err != nil {
+					return nil, err
+				}
+				resp.File = append(resp.File, &plugin.CodeGeneratorResponse_File{
+					Name:    proto.String(path.Join(path.Dir(name), serv.GetName()+".md")),
+					Content: proto.String(bs),
+				})
+			}
+		}
+	}
+
+	for _, pkg := range pkgs {
+		file, err := genRuntimeFile(pkgFile[pkg], opts)
+		if err != nil {
+			return nil, err
+		}
+		resp.File = append(resp.File, file)
+
+		if opts.OpenAPI != "" && opts.OpenAPIOut == openapiOutPackage {
+			fds := pkgServFiles[pkg]
+			goPkg, _ := descInfo.GoPackage(fds[0])
+			name, err := outputFileName(fds[0], ".api.go", opts)
+			if err != nil {
+				return nil, err
+			}
+			file, err := genOpenAPIFile(fds, path.Join(path.Dir(name), goPkg.Name+".openapi."+opts.OpenAPI), opts)
+			if err != nil {
+				return nil, err
+			}
+			resp.File = append(resp.File, file)
+		}
 	}
 
 	return &resp, nil
 }
 
-func parseRestFile(fd *descriptor.FileDescriptorProto) (*FileData, error) {
-	pkg := fd.Options.GetGoPackage()
-	ps := strings.Split(pkg, "/")
+// genOpenAPIFile generates the OpenAPI description of the services of fds,
+// titled after the proto package of the first one.
+func genOpenAPIFile(fds []*descriptor.FileDescriptorProto, name string, opts *Options) (*plugin.CodeGeneratorResponse_File, error) {
+	title := fds[0].GetPackage()
+	if title == "" {
+		title = fds[0].GetName()
+	}
+	doc := newOpenAPIDoc(title, opts)
+	for _, fd := range fds {
+		if err := doc.addFile(fd); err != nil {
+			return nil, fmt.Errorf("%s: %v", fd.GetName(), err)
+		}
+	}
+	content, err := doc.marshal(opts.OpenAPI)
+	if err != nil {
+		return nil, err
+	}
+	return &plugin.CodeGeneratorResponse_File{
+		Name:    proto.String(name),
+		Content: proto.String(content),
+	}, nil
+}
+
+// genRuntimeFile generates the file holding the declarations shared by all
+// the files of fd's Go package, such as APIError. It's named after the
+// package, not fd, so generating the package file by file yields one copy.
+func genRuntimeFile(fd *descriptor.FileDescriptorProto, opts *Options) (*plugin.CodeGeneratorResponse_File, error) {
+	pkg, err := descInfo.GoPackage(fd)
+	if err != nil {
+		return nil, err
+	}
+	imports := newImportSet(pkg.Path, runtimeImports)
+	data := &RuntimeData{
+		Version:   Release,
+		GoPackage: pkg.Name,
+		Server:    opts.server(),
+		Backends:  opts.backends(),
+		Fake:      opts.Fake,
+
+		QueryStyle:    opts.QueryStyle,
+		FormSeparator: opts.FormSeparator,
+	}
+	if opts.ErrorType == "" {
+		data.ErrTyp = imports.add(pbinfo.ImportSpec{Name: "statuspb", Path: statusPkg}) + ".Status"
+	} else {
+		data.ErrTyp, err = imports.typeName(opts.ErrorType)
+		if err != nil {
+			return nil, fmt.Errorf("error_type: %v", err)
+		}
+	}
+	data.Imports = imports.list()
+
+	bs, err := getRuntimeContent(data)
+	if err != nil {
+		return nil, err
+	}
+	name, err := outputFileName(fd, ".api.go", opts)
+	if err != nil {
+		return nil, err
+	}
+	return goFile(path.Join(path.Dir(name), runtimeFileName), bs)
+}
+
+// goFile reports the generated Go file name, formatted like gofmt does.
+func goFile(name, content string) (*plugin.CodeGeneratorResponse_File, error) {
+	bs, err := format.Source([]byte(content))
+	if err != nil {
+		return nil, fmt.Errorf("format %s: %v", name, err)
+	}
+	return &plugin.CodeGeneratorResponse_File{
+		Name:    proto.String(name),
+		Content: proto.String(string(bs)),
+	}, nil
+}
+
+func parseRestFile(fd *descriptor.FileDescriptorProto, opts *Options) (*FileData, error) {
+	pkg, err := descInfo.GoPackage(fd)
+	if err != nil {
+		return nil, err
+	}
 	data := &FileData{
 		Version:   Release,
 		Source:    fd.GetName(),
-		GoPackage: strings.ReplaceAll(ps[len(ps)-1], "-", "_"),
+		GoPackage: pkg.Name,
+
+		RawResponse: opts.RawResponse,
+		Backends:    opts.backends(),
+	}
+	imports := newImportSet(pkg.Path, fixedImports)
+	srvImports := newImportSet(pkg.Path, serverImports)
+	for _, b := range data.Backends {
+		// Added first to keep their names, the registration functions use them.
+		if b.Import.Path != "" && strings.HasPrefix(b.Router, b.Import.Name+".") {
+			srvImports.add(b.Import)
+		}
 	}
 	servs := fd.GetService()
 
 	for _, serv := range servs {
-		srv, err := parseRestService(fd, serv)
+		if opts.omitService(serv) {
+			continue
+		}
+		srv, err := parseRestService(fd, serv, imports, srvImports, opts)
 		if err != nil {
 			return nil, err
 		}
+		for _, m := range srv.Methods {
+			srv.MockMethods = append(srv.MockMethods, m)
+			if opts.RawResponse {
+				srv.MockMethods = append(srv.MockMethods, &MethodData{
+					ServName: m.ServName,
+					MethName: m.RawName,
+					ReqTyp:   m.ReqTyp,
+					ResTyp:   "grequests.Response",
+				})
+			}
+		}
 		data.Services = append(data.Services, srv)
 	}
+	data.Imports = imports.list()
+	data.ServerImports = srvImports.list()
+	// The fake only refers to the message types, not to the routers.
+	for _, imp := range data.ServerImports {
+		if !isBackendImport(imp.Path) {
+			data.FakeImports = append(data.FakeImports, imp)
+		}
+	}
 
 	return data, nil
 }
 
-func parseRestService(fd *descriptor.FileDescriptorProto, serv *descriptor.ServiceDescriptorProto) (*ServiceData, error) {
+func parseRestService(fd *descriptor.FileDescriptorProto, serv *descriptor.ServiceDescriptorProto, imports, srvImports *importSet, opts *Options) (*ServiceData, error) {
 	data := &ServiceData{
 		PkgName:  fd.GetPackage(),
 		ServName: strings.ReplaceAll(serv.GetName(), "Service", ""),
+		BaseURL:  baseURL(fd, serv),
+	}
+	if c := getComment(serv); c != "" {
+		data.Doc = goDoc(data.ServName+"Service", c, nil)
+	}
+	if serv.GetOptions().GetDeprecated() {
+		data.Deprecated = true
+		data.Doc = deprecatedDoc(data.Doc)
 	}
 
 	meths := serv.GetMethod()
 	for _, meth := range meths {
-		mth, err := parseRestMethod(fd, serv, meth)
+		if opts.omitMethod(meth) {
+			continue
+		}
+		info := getHTTPInfo(meth)
+		if info != nil {
+			for _, rule := range append([]*httpInfo{info}, info.bindings...) {
+				if rule.legacyFormat {
+					warnf("method %s.%s: body %q uses the deprecated \"field,format\" syntax, set (goapi.method).body_encoding instead", serv.GetName(), meth.GetName(), rule.body+","+rule.format)
+				}
+			}
+		}
+		if info == nil {
+			switch opts.Unannotated {
+			case unannotatedError:
+				return nil, fmt.Errorf("method %s.%s has no google.api.http annotation", serv.GetName(), meth.GetName())
+			case unannotatedPost:
+				info = defaultHTTPInfo(fd, serv, meth)
+			default:
+				continue
+			}
+		}
+		mth, err := parseRestMethod(fd, serv, meth, info, meth.GetName(), getComment(meth), imports, opts)
 		if err != nil {
 			return nil, err
 		}
+		mth.Doc = goDoc(mth.MethName, mth.Comment, getDetachedComments(meth))
+		if mth.Deprecated {
+			mth.Doc = deprecatedDoc(mth.Doc)
+		}
 		data.Methods = append(data.Methods, mth)
+
+		// Every additional binding gets its own variant, <Method>Binding<N>.
+		for i, binding := range info.bindings {
+			name := fmt.Sprintf("%sBinding%d", meth.GetName(), i+1)
+			comment := fmt.Sprintf("is like %s, but sends the request to %s %s from additional_bindings.", meth.GetName(), strings.ToUpper(binding.verb), binding.url)
+			mth, err := parseRestMethod(fd, serv, meth, binding, name, comment, imports, opts)
+			if err != nil {
+				return nil, err
+			}
+			mth.Doc = goDoc(name, comment, nil)
+			if mth.Deprecated {
+				mth.Doc = deprecatedDoc(mth.Doc)
+			}
+			data.Methods = append(data.Methods, mth)
+		}
+
+		if opts.server() {
+			if err := parseServerMethod(data, meth, info, srvImports, opts); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	return data, nil
 }
 
-func parseRestMethod(fd *descriptor.FileDescriptorProto, serv *descriptor.ServiceDescriptorProto, meth *descriptor.MethodDescriptorProto) (*MethodData, error) {
+// baseURL reports the URL the client of serv sends its requests to by
+// default: the (goapi.service).base_url option, else the https URL of
+// google.api.default_host, else https://{package} for compatibility.
+func baseURL(fd *descriptor.FileDescriptorProto, serv *descriptor.ServiceDescriptorProto) string {
+	if serv.GetOptions() != nil {
+		o, _ := proto.GetExtension(serv.GetOptions(), goapipb.E_Service).(*goapipb.ServiceOptions)
+		if u := o.GetBaseUrl(); u != "" {
+			return strings.TrimSuffix(u, "/")
+		}
+		if host, _ := proto.GetExtension(serv.GetOptions(), annotations.E_DefaultHost).(string); host != "" {
+			if !strings.Contains(host, "://") {
+				host = "https://" + host
+			}
+			return strings.TrimSuffix(host, "/")
+		}
+	}
+	return "https://" + fd.GetPackage()
+}
+
+// defaultHTTPInfo derives the conventional route of a method without
+// google.api.http, POST /{package}.{Service}/{Method} with the request as body.
+func defaultHTTPInfo(fd *descriptor.FileDescriptorProto, serv *descriptor.ServiceDescriptorProto, meth *descriptor.MethodDescriptorProto) *httpInfo {
+	name := serv.GetName()
+	if fd.GetPackage() != "" {
+		name = fd.GetPackage() + "." + name
+	}
+	return &httpInfo{
+		verb: "post",
+		url:  fmt.Sprintf("/%s/%s", name, meth.GetName()),
+		body: "*",
+	}
+}
+
+// methodRules reports the HTTP rules the client calls meth through, the
+// rule of its annotation followed by the additional bindings, with the
+// Go method name of each. It reports none for the methods the client
+// skips or can't call over HTTP, such as streaming or omitted deprecated ones.
+func methodRules(fd *descriptor.FileDescriptorProto, serv *descriptor.ServiceDescriptorProto, meth *descriptor.MethodDescriptorProto, opts *Options) ([]*httpInfo, []string) {
+	if meth.GetClientStreaming() || meth.GetServerStreaming() || opts.omitService(serv) || opts.omitMethod(meth) {
+		return nil, nil
+	}
+	info := getHTTPInfo(meth)
+	if info == nil {
+		if opts.Unannotated != unannotatedPost {
+			return nil, nil
+		}
+		info = defaultHTTPInfo(fd, serv, meth)
+	}
+	rules := append([]*httpInfo{info}, info.bindings...)
+	names := []string{meth.GetName()}
+	for i := range info.bindings {
+		names = append(names, fmt.Sprintf("%sBinding%d", meth.GetName(), i+1))
+	}
+	return rules, names
+}
+
+// parseRestMethod parses meth called through the HTTP rule info as the Go method name.
+func parseRestMethod(fd *descriptor.FileDescriptorProto, serv *descriptor.ServiceDescriptorProto, meth *descriptor.MethodDescriptorProto, info *httpInfo, name, comment string, imports *importSet, opts *Options) (*MethodData, error) {
+	reqTyp, err := imports.typeName(meth.GetInputType())
+	if err != nil {
+		return nil, err
+	}
+	resTyp, err := imports.typeName(meth.GetOutputType())
+	if err != nil {
+		return nil, err
+	}
 	data := &MethodData{
 		ServName: strings.ReplaceAll(serv.GetName(), "Service", ""),
-		MethName: meth.GetName(),
-		Comment:  getComment(meth),
-		ReqTyp:   typeName(meth.GetInputType()),
-		ResTyp:   typeName(meth.GetOutputType()),
+		MethName: name,
+		Comment:  comment,
+		ReqTyp:   reqTyp,
+		ResTyp:   resTyp,
+		RawName:  unexport(name) + "Raw",
+
+		Deprecated: meth.GetOptions().GetDeprecated(),
+	}
+	if opts.RawResponse {
+		data.RawName = name + "Raw"
+	}
+	if d := opts.methodTimeout(serv.GetName(), meth); d > 0 {
+		data.Timeout = durationExpr(d)
 	}
 	switch {
 	case meth.GetClientStreaming():
@@ -93,16 +433,70 @@ func parseRestMethod(fd *descriptor.FileDescriptorProto, serv *descriptor.Servic
 	case meth.GetServerStreaming():
 		data.ReqCode = fmt.Sprintf(noServerStream, meth.GetName())
 	default:
-		code, err := genRestMethodCode(fd, serv, meth)
+		code, err := genRestMethodCode(fd, serv, meth, info, opts)
 		if err != nil {
 			return nil, err
 		}
 		data.ReqCode = code
 	}
+	code, err := genRestResponseCode(meth, info, resTyp)
+	if err != nil {
+		return nil, err
+	}
+	data.ResCode = code
 
 	return data, nil
 }
 
+// outputFileName reports the name of the file generated for fd with the given
+// suffix, laid out the same way protoc-gen-go lays out the .pb.go files.
+func outputFileName(fd *descriptor.FileDescriptorProto, suffix string, opts *Options) (string, error) {
+	base := strings.TrimSuffix(fd.GetName(), ".proto")
+	if opts.Paths == pathsSourceRelative {
+		return base + suffix, nil
+	}
+
+	pkg, err := descInfo.GoPackage(fd)
+	if err != nil {
+		return "", err
+	}
+	name := path.Join(pkg.Path, path.Base(base)) + suffix
+	if opts.Module == "" {
+		return name, nil
+	}
+	prefix := opts.Module + "/"
+	if !strings.HasPrefix(name, prefix) {
+		return "", fmt.Errorf("output file %q of %q does not match prefix %q", name, fd.GetName(), opts.Module)
+	}
+	return strings.TrimPrefix(name, prefix), nil
+}
+
+// durationExpr reports the Go expression of d, e.g. 1500*time.Millisecond.
+func durationExpr(d time.Duration) string {
+	units := []struct {
+		unit time.Duration
+		name string
+	}{
+		{time.Hour, "time.Hour"},
+		{time.Minute, "time.Minute"},
+		{time.Second, "time.Second"},
+		{time.Millisecond, "time.Millisecond"},
+		{time.Microsecond, "time.Microsecond"},
+	}
+	for _, u := range units {
+		if d%u.unit == 0 {
+			return fmt.Sprintf("%d*%s", d/u.unit, u.name)
+		}
+	}
+	return fmt.Sprintf("time.Duration(%d)", d)
+}
+
+// warnf reports a problem that doesn't stop the generation on stderr,
+// which protoc passes on.
+func warnf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "protoc-gen-go_api: warning: "+format+"\n", args...)
+}
+
 func strContains(a []string, s string) bool {
 	for _, as := range a {
 		if as == s {
@@ -111,8 +505,3 @@ func strContains(a []string, s string) bool {
 	}
 	return false
 }
-
-func typeName(str string) string {
-	sp := strings.Split(str, ".")
-	return sp[len(sp)-1]
-}
@@ -0,0 +1,88 @@
+package goapi
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/open-api-go/protoc-gen-go_api/pbinfo"
+)
+
+var (
+	// fixedImports are imported by every generated file, see goapiTmpl,
+	// or by its mocks, see mockTmpl.
+	fixedImports = []string{"context", "fmt", "json", "strings", "grequests", "protojson", "time", "url", "base64", "sync", "testing"}
+	// runtimeImports are imported by every runtime file, see runtimeTmpl.
+	runtimeImports = []string{"errors", "fmt", "http", "strings", "grequests", "protojson", "codepb", "context",
+		"bytes", "multipart", "textproto", "sort", "time",
+		"base64", "json", "io", "url", "regexp", "strconv", "sync", "proto", "protoreflect", "gin", "echo", "chi"}
+	// serverImports are imported by every server or fake file,
+	// see serverTmpl and fakeTmpl.
+	serverImports = []string{"context", "http", "regexp", "httptest", "grequests"}
+)
+
+// importSet collects the Go packages referenced by a generated file,
+// so the import block only lists what the file uses.
+type importSet struct {
+	self  string                       // import path of the generated file
+	specs map[string]pbinfo.ImportSpec // by import path
+	names map[string]string            // import path by package name
+}
+
+// newImportSet creates an importSet for a file in package self,
+// which already imports the packages named fixed.
+func newImportSet(self string, fixed []string) *importSet {
+	s := &importSet{
+		self:  self,
+		specs: map[string]pbinfo.ImportSpec{},
+		names: map[string]string{},
+	}
+	for _, n := range fixed {
+		s.names[n] = ""
+	}
+	return s
+}
+
+// typeName reports the Go identifier of the fully qualified proto type typ,
+// e.g. ".foo.v1.Outer.Inner" -> "foopb.Outer_Inner", and records its import.
+func (s *importSet) typeName(typ string) (string, error) {
+	t, ok := descInfo.Type[typ]
+	if !ok {
+		return "", fmt.Errorf("unknown type %q", typ)
+	}
+	name, imp, err := descInfo.NameSpec(t)
+	if err != nil {
+		return "", err
+	}
+	if imp.Path == s.self {
+		return name, nil
+	}
+	return s.add(imp) + "." + name, nil
+}
+
+// add records imp and reports the name it's imported as,
+// which differs from imp.Name if another package already took that name.
+func (s *importSet) add(imp pbinfo.ImportSpec) string {
+	if spec, ok := s.specs[imp.Path]; ok {
+		return spec.Name
+	}
+	name := imp.Name
+	for i := 1; ; i++ {
+		if _, taken := s.names[name]; !taken {
+			break
+		}
+		name = fmt.Sprintf("%s%d", imp.Name, i)
+	}
+	s.names[name] = imp.Path
+	s.specs[imp.Path] = pbinfo.ImportSpec{Name: name, Path: imp.Path}
+	return name
+}
+
+// list reports the recorded imports sorted by path.
+func (s *importSet) list() []pbinfo.ImportSpec {
+	specs := make([]pbinfo.ImportSpec, 0, len(s.specs))
+	for _, spec := range s.specs {
+		specs = append(specs, spec)
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Path < specs[j].Path })
+	return specs
+}
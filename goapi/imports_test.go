@@ -0,0 +1,46 @@
+package goapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/open-api-go/protoc-gen-go_api/pbinfo"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestImportSetTypeName(t *testing.T) {
+	file := func(name, pkg, goPkg, msg string) *descriptor.FileDescriptorProto {
+		return &descriptor.FileDescriptorProto{
+			Name:        proto.String(name),
+			Package:     proto.String(pkg),
+			Options:     &descriptor.FileOptions{GoPackage: proto.String(goPkg)},
+			MessageType: []*descriptor.DescriptorProto{{Name: proto.String(msg)}},
+		}
+	}
+	defer func(info pbinfo.Info) { descInfo = info }(descInfo)
+	descInfo = pbinfo.Of([]*descriptor.FileDescriptorProto{
+		file("library/v1/library.proto", "library.v1", "example.com/m/library/v1", "Book"),
+		file("common/v2/common.proto", "common.v2", "example.com/m/common/v2", "Shelf"),
+		file("other/common.proto", "other", "example.com/other/common;common", "Shelf"),
+	})
+
+	s := newImportSet("example.com/m/library/v1", fixedImports)
+	for _, tt := range []struct{ typ, want string }{
+		{".library.v1.Book", "Book"},
+		{".common.v2.Shelf", "commonpb.Shelf"},
+		{".other.Shelf", "commonpb1.Shelf"},
+	} {
+		got, err := s.typeName(tt.typ)
+		if err != nil || got != tt.want {
+			t.Errorf("typeName(%q) = %q %v, want %q", tt.typ, got, err, tt.want)
+		}
+	}
+	want := []pbinfo.ImportSpec{
+		{Name: "commonpb", Path: "example.com/m/common/v2"},
+		{Name: "commonpb1", Path: "example.com/other/common"},
+	}
+	if got := s.list(); !reflect.DeepEqual(got, want) {
+		t.Errorf("list() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,659 @@
+package goapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	goapipb "github.com/open-api-go/protoc-gen-go_api/goapi/annotations"
+	"google.golang.org/genproto/googleapis/api/annotations"
+)
+
+const (
+	openapiVersion = "3.1.0"
+	openapiRef     = "#/components/schemas/"
+	statusValue    = "google.rpc.Status"
+)
+
+// openapiDoc builds the OpenAPI description of the HTTP rules of the
+// services of one or more proto files. Messages and enums are described
+// once in components.schemas, keyed by their full proto name, and
+// referred to from the operations.
+type openapiDoc struct {
+	opts    *Options
+	title   string
+	tags    []interface{}
+	paths   *object
+	schemas map[string]*object
+	ops     map[string]string  // "VERB path" to the operationId on it
+	servers []openapiServer    // the base URL of every operation
+	schemes map[string]*object // components.securitySchemes by name
+}
+
+// openapiServer is an operation and the base URL of its service.
+type openapiServer struct {
+	op  *object
+	url string
+}
+
+func newOpenAPIDoc(title string, opts *Options) *openapiDoc {
+	return &openapiDoc{
+		opts:    opts,
+		title:   title,
+		paths:   newObject(),
+		schemas: map[string]*object{},
+		ops:     map[string]string{},
+		schemes: map[string]*object{},
+	}
+}
+
+// addFile adds the services of fd, one tag per service and one operation
+// per HTTP rule, additional bindings included. The methods the client
+// skips, streaming or unannotated ones, are left out too.
+func (d *openapiDoc) addFile(fd *descriptor.FileDescriptorProto) error {
+	for _, serv := range fd.GetService() {
+		if d.opts.omitService(serv) {
+			continue
+		}
+		tag := newObject("name", serv.GetName())
+		if c := getDescription(serv); c != "" {
+			tag.set("description", c)
+		}
+		d.tags = append(d.tags, tag)
+
+		for _, meth := range serv.GetMethod() {
+			rules, names := methodRules(fd, serv, meth, d.opts)
+			for i, rule := range rules {
+				op, err := d.addOperation(serv, meth, rule, names[i])
+				if err != nil {
+					return err
+				}
+				d.servers = append(d.servers, openapiServer{op, baseURL(fd, serv)})
+			}
+		}
+	}
+	return nil
+}
+
+// paramNameRe matches the parameters of an OpenAPI path.
+var paramNameRe = regexp.MustCompile(`\{[^}]*\}`)
+
+// addOperation adds meth served on the HTTP rule info, name is the client
+// method calling it and makes the operationId unique.
+func (d *openapiDoc) addOperation(serv *descriptor.ServiceDescriptorProto, meth *descriptor.MethodDescriptorProto, info *httpInfo, name string) (*object, error) {
+	tmpl, err := parsePathTemplate(info.url)
+	if err != nil {
+		return nil, fmt.Errorf("method %q: %v", meth.GetName(), err)
+	}
+	p, pathParams, err := tmpl.openapiPath()
+	if err != nil {
+		return nil, fmt.Errorf("method %q: %v", meth.GetName(), err)
+	}
+	verb := strings.ToLower(info.verb)
+	opID := serv.GetName() + "_" + name
+	// Paths differing only in the parameter names are the same path.
+	key := strings.ToUpper(verb) + " " + paramNameRe.ReplaceAllString(p, "{}")
+	if prev, ok := d.ops[key]; ok {
+		return nil, fmt.Errorf("openapi: %s and %s are both served on %s %s", prev, opID, strings.ToUpper(verb), p)
+	}
+	d.ops[key] = opID
+
+	op := newObject("tags", []interface{}{serv.GetName()}, "operationId", opID)
+	if c := getDescription(meth); c != "" {
+		op.set("description", c)
+	}
+	if meth.GetOptions().GetDeprecated() || serv.GetOptions().GetDeprecated() {
+		op.set("deprecated", true)
+	}
+	if auth := getMethodOptions(meth).GetAuth(); auth.GetScheme() != goapipb.Auth_SCHEME_UNSPECIFIED {
+		name, scheme := securityScheme(auth)
+		d.schemes[name] = scheme
+		op.set("security", []interface{}{newObject(name, []interface{}{})})
+	}
+
+	var params []interface{}
+	for _, pp := range pathParams {
+		field := lookupField(meth.GetInputType(), pp.v.fieldPath)
+		param := newObject("name", pp.name, "in", "path", "required", true)
+		desc := getDescription(field)
+		schema := d.fieldSchema(field)
+		rest := tmpl.segments[pp.seg] == "**"
+		switch {
+		case !pp.whole():
+			// Part of the field value, the literals of which are in the path.
+			format := "Segment of `%[1]s`, which matches `%[2]s`. %[3]s"
+			if rest {
+				format = "Rest of `%[1]s`, `/` included. `%[1]s` matches `%[2]s`. %[3]s"
+			}
+			desc = fmt.Sprintf(format, pp.v.fieldPath, tmpl.variableTemplate(pp.v), desc)
+			schema = newObject("type", "string")
+		case rest:
+			desc = "The rest of the path, `/` included. " + desc
+		}
+		if pp.whole() && pp.name != pp.v.fieldPath {
+			desc = fmt.Sprintf("`%s`. %s", pp.v.fieldPath, desc)
+		}
+		if desc = strings.TrimSpace(desc); desc != "" {
+			param.set("description", desc)
+		}
+		if field.GetOptions().GetDeprecated() {
+			param.set("deprecated", true)
+		}
+		param.set("schema", schema)
+		params = append(params, param)
+	}
+	query := queryParams(meth, info)
+	var names []string
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field := query[name]
+		param := newObject("name", queryParamName(name, field, d.opts.QueryStyle), "in", "query")
+		if isRequired(field) {
+			param.set("required", true)
+		}
+		if d.opts.QueryStyle == queryComma && field.GetLabel() == fieldLabelRepeated {
+			param.set("explode", false)
+		}
+		if c := getDescription(field); c != "" {
+			param.set("description", c)
+		}
+		if field.GetOptions().GetDeprecated() {
+			param.set("deprecated", true)
+		}
+		param.set("schema", d.fieldSchema(field))
+		params = append(params, param)
+	}
+	if len(params) > 0 {
+		op.set("parameters", params)
+	}
+
+	if info.body != "" {
+		var schema *object
+		if info.body == "*" {
+			schema = d.typeRef(meth.GetInputType())
+		} else {
+			schema = d.fieldSchema(lookupField(meth.GetInputType(), info.body))
+		}
+		format := info.format
+		if format == "" {
+			format = d.opts.DefaultBody
+		}
+		op.set("requestBody", newObject(
+			"required", true,
+			"content", newObject(bodyContentType(format), newObject("schema", schema)),
+		))
+	}
+
+	var res *object
+	switch {
+	case meth.GetOutputType() == httpBodyType:
+		res = newObject("*/*", newObject("schema", newObject("type", "string", "format", "binary")))
+	case info.responseBody != "":
+		res = newObject("application/json", newObject("schema", d.fieldSchema(lookupField(meth.GetOutputType(), info.responseBody))))
+	default:
+		res = newObject("application/json", newObject("schema", d.typeRef(meth.GetOutputType())))
+	}
+	op.set("responses", newObject(
+		"200", newObject("description", "OK", "content", res),
+		"default", newObject(
+			"description", "Error",
+			"content", newObject("application/json", newObject("schema", d.errorRef())),
+		),
+	))
+
+	item, _ := d.paths.get(p).(*object)
+	if item == nil {
+		item = newObject()
+		d.paths.set(p, item)
+	}
+	item.set(verb, op)
+	return op, nil
+}
+
+// securityScheme reports the name and the OpenAPI security scheme of
+// the (goapi.method).auth option auth.
+func securityScheme(auth *goapipb.Auth) (string, *object) {
+	switch auth.GetScheme() {
+	case goapipb.Auth_SCHEME_BASIC:
+		return "basicAuth", newObject("type", "http", "scheme", "basic")
+	case goapipb.Auth_SCHEME_API_KEY:
+		in, name := "header", "apiKey_"+auth.GetName()
+		if auth.GetInQuery() {
+			in, name = "query", "apiKeyQuery_"+auth.GetName()
+		}
+		return name, newObject("type", "apiKey", "in", in, "name", auth.GetName())
+	}
+	return "bearerAuth", newObject("type", "http", "scheme", "bearer")
+}
+
+// bodyContentType reports the media type of the body format.
+func bodyContentType(format string) string {
+	switch format {
+	case bodyFORM:
+		return "application/x-www-form-urlencoded"
+	case bodyMULTI:
+		return "multipart/form-data"
+	}
+	return "application/json"
+}
+
+// errorRef reports the schema of the body of non-2xx responses.
+func (d *openapiDoc) errorRef() *object {
+	if d.opts.ErrorType != "" {
+		return d.typeRef(d.opts.ErrorType)
+	}
+	if descInfo.Type["."+statusValue] != nil {
+		return d.typeRef("." + statusValue)
+	}
+	// status.proto is rarely among the files protoc passes on.
+	d.schemas[statusValue] = newObject(
+		"type", "object",
+		"description", "The error returned by the service.",
+		"properties", newObject(
+			"code", newObject("type", "integer", "format", "int32"),
+			"message", newObject("type", "string"),
+			"details", newObject("type", "array", "items", newObject("type", "object")),
+		),
+	)
+	return newObject("$ref", openapiRef+statusValue)
+}
+
+// fieldSchema reports the schema of the value of field in protojson.
+func (d *openapiDoc) fieldSchema(field *descriptor.FieldDescriptorProto) *object {
+	if entry := mapEntry(field); entry != nil {
+		return newObject("type", "object", "additionalProperties", d.scalarSchema(entry.GetField()[1]))
+	}
+	schema := d.scalarSchema(field)
+	if field.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED {
+		return newObject("type", "array", "items", schema)
+	}
+	return schema
+}
+
+// mapEntry reports the entry message of the map field, nil if field is no map.
+func mapEntry(field *descriptor.FieldDescriptorProto) *descriptor.DescriptorProto {
+	if field.GetType() != fieldTypeMessage || field.GetLabel() != descriptor.FieldDescriptorProto_LABEL_REPEATED {
+		return nil
+	}
+	m, _ := descInfo.Type[field.GetTypeName()].(*descriptor.DescriptorProto)
+	if !m.GetOptions().GetMapEntry() {
+		return nil
+	}
+	return m
+}
+
+// scalarSchema reports the schema of a single value of field.
+func (d *openapiDoc) scalarSchema(field *descriptor.FieldDescriptorProto) *object {
+	switch field.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+		return newObject("type", "number", "format", "double")
+	case descriptor.FieldDescriptorProto_TYPE_FLOAT:
+		return newObject("type", "number", "format", "float")
+	case descriptor.FieldDescriptorProto_TYPE_INT32,
+		descriptor.FieldDescriptorProto_TYPE_SINT32,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+		return newObject("type", "integer", "format", "int32")
+	case descriptor.FieldDescriptorProto_TYPE_UINT32,
+		descriptor.FieldDescriptorProto_TYPE_FIXED32:
+		return newObject("type", "integer", "format", "uint32")
+	// protojson writes 64-bit integers as strings.
+	case descriptor.FieldDescriptorProto_TYPE_INT64,
+		descriptor.FieldDescriptorProto_TYPE_SINT64,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+		return newObject("type", "string", "format", "int64")
+	case descriptor.FieldDescriptorProto_TYPE_UINT64,
+		descriptor.FieldDescriptorProto_TYPE_FIXED64:
+		return newObject("type", "string", "format", "uint64")
+	case fieldTypeBool:
+		return newObject("type", "boolean")
+	case fieldTypeString:
+		return newObject("type", "string")
+	case fieldTypeBytes:
+		return newObject("type", "string", "format", "byte")
+	}
+	return d.typeRef(field.GetTypeName())
+}
+
+// wellKnownSchemas are the schemas of the well-known types protojson
+// writes in a special form.
+var wellKnownSchemas = map[string]func() *object{
+	".google.protobuf.Timestamp": func() *object { return newObject("type", "string", "format", "date-time") },
+	".google.protobuf.Duration": func() *object {
+		return newObject("type", "string", "pattern", `^-?[0-9]+(\.[0-9]+)?s$`)
+	},
+	".google.protobuf.FieldMask":   func() *object { return newObject("type", "string") },
+	".google.protobuf.DoubleValue": func() *object { return newObject("type", "number", "format", "double") },
+	".google.protobuf.FloatValue":  func() *object { return newObject("type", "number", "format", "float") },
+	".google.protobuf.Int64Value":  func() *object { return newObject("type", "string", "format", "int64") },
+	".google.protobuf.UInt64Value": func() *object { return newObject("type", "string", "format", "uint64") },
+	".google.protobuf.Int32Value":  func() *object { return newObject("type", "integer", "format", "int32") },
+	".google.protobuf.UInt32Value": func() *object { return newObject("type", "integer", "format", "uint32") },
+	".google.protobuf.BoolValue":   func() *object { return newObject("type", "boolean") },
+	".google.protobuf.StringValue": func() *object { return newObject("type", "string") },
+	".google.protobuf.BytesValue":  func() *object { return newObject("type", "string", "format", "byte") },
+	".google.protobuf.Struct":      func() *object { return newObject("type", "object") },
+	".google.protobuf.Value":       func() *object { return newObject() },
+	".google.protobuf.ListValue":   func() *object { return newObject("type", "array") },
+	".google.protobuf.Empty":       func() *object { return newObject("type", "object") },
+	".google.protobuf.Any": func() *object {
+		return newObject("type", "object", "properties", newObject("@type", newObject("type", "string")))
+	},
+}
+
+// typeRef reports the schema of the message or enum typeName, a reference
+// to its component schema except for the well-known types.
+func (d *openapiDoc) typeRef(typeName string) *object {
+	if wk, ok := wellKnownSchemas[typeName]; ok {
+		return wk()
+	}
+	key := strings.TrimPrefix(typeName, ".")
+	if _, ok := d.schemas[key]; !ok {
+		// Reserve the key first, messages may refer to themselves.
+		d.schemas[key] = nil
+		switch t := descInfo.Type[typeName].(type) {
+		case *descriptor.DescriptorProto:
+			d.schemas[key] = d.messageSchema(t)
+		case *descriptor.EnumDescriptorProto:
+			d.schemas[key] = enumSchema(t)
+		default:
+			d.schemas[key] = newObject()
+		}
+	}
+	return newObject("$ref", openapiRef+key)
+}
+
+// messageSchema reports the component schema of m, properties being named
+// after the json names of the fields.
+func (d *openapiDoc) messageSchema(m *descriptor.DescriptorProto) *object {
+	schema := newObject("type", "object")
+	if c := getDescription(m); c != "" {
+		schema.set("description", c)
+	}
+	props := newObject()
+	var required []interface{}
+	for _, field := range m.GetField() {
+		prop := d.fieldSchema(field)
+		if c := getDescription(field); c != "" {
+			prop.set("description", c)
+		}
+		switch {
+		case hasFieldBehavior(field, annotations.FieldBehavior_OUTPUT_ONLY):
+			prop.set("readOnly", true)
+		case hasFieldBehavior(field, annotations.FieldBehavior_INPUT_ONLY):
+			prop.set("writeOnly", true)
+		}
+		if field.GetOptions().GetDeprecated() {
+			prop.set("deprecated", true)
+		}
+		if isRequired(field) {
+			required = append(required, field.GetJsonName())
+		}
+		props.set(field.GetJsonName(), prop)
+	}
+	if len(props.keys) > 0 {
+		schema.set("properties", props)
+	}
+	if len(required) > 0 {
+		schema.set("required", required)
+	}
+	return schema
+}
+
+// enumSchema reports the component schema of e, the value names as
+// protojson writes them. The comments of the values are listed in the
+// description, JSON Schema has no place for them.
+func enumSchema(e *descriptor.EnumDescriptorProto) *object {
+	var names []interface{}
+	var desc []string
+	if c := getDescription(e); c != "" {
+		desc = append(desc, c, "")
+	}
+	for _, v := range e.GetValue() {
+		names = append(names, v.GetName())
+		if c := getDescription(v); c != "" {
+			desc = append(desc, fmt.Sprintf("- %s: %s", v.GetName(), strings.ReplaceAll(c, "\n", " ")))
+		}
+	}
+	schema := newObject("type", "string", "enum", names)
+	if s := strings.TrimSpace(strings.Join(desc, "\n")); s != "" {
+		schema.set("description", s)
+	}
+	return schema
+}
+
+var apiVersionRe = regexp.MustCompile(`^v[0-9]+((alpha|beta)[0-9]*)?$`)
+
+// document reports the OpenAPI document.
+func (d *openapiDoc) document() *object {
+	version := "0.0.0"
+	if i := strings.LastIndexByte(d.title, '.'); i >= 0 && apiVersionRe.MatchString(d.title[i+1:]) {
+		version = d.title[i+1:]
+	}
+	doc := newObject(
+		"openapi", openapiVersion,
+		"info", newObject("title", d.title, "version", version),
+	)
+	// One base URL is the servers of the document, several are set on
+	// every operation.
+	urls := map[string]bool{}
+	for _, srv := range d.servers {
+		urls[srv.url] = true
+	}
+	if len(urls) == 1 {
+		doc.set("servers", []interface{}{newObject("url", d.servers[0].url)})
+	} else {
+		for _, srv := range d.servers {
+			srv.op.set("servers", []interface{}{newObject("url", srv.url)})
+		}
+	}
+	if len(d.tags) > 0 {
+		doc.set("tags", d.tags)
+	}
+	doc.set("paths", d.paths)
+	components := newObject()
+	if len(d.schemas) > 0 {
+		components.set("schemas", sortedObject(d.schemas))
+	}
+	if len(d.schemes) > 0 {
+		components.set("securitySchemes", sortedObject(d.schemes))
+	}
+	if len(components.keys) > 0 {
+		doc.set("components", components)
+	}
+	return doc
+}
+
+// sortedObject reports m as an object with sorted keys.
+func sortedObject(m map[string]*object) *object {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	o := newObject()
+	for _, k := range keys {
+		o.set(k, m[k])
+	}
+	return o
+}
+
+// marshal reports the document encoded as format, yaml or json.
+func (d *openapiDoc) marshal(format string) (string, error) {
+	doc := d.document()
+	if format == openapiJSON {
+		var b bytes.Buffer
+		enc := json.NewEncoder(&b)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(doc); err != nil {
+			return "", err
+		}
+		return b.String(), nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Code generated by protoc-gen-go_api(github.com/open-api-go/protoc-gen-go_api version=%s). DO NOT EDIT.\n", Release)
+	writeYAML(&b, doc, "", "")
+	return b.String(), nil
+}
+
+// object is a JSON object keeping its keys in insertion order, so the
+// documents read in a stable, meaningful order. Values are strings, bools,
+// []interface{} or *object.
+type object struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// newObject makes an object of the key value pairs kv.
+func newObject(kv ...interface{}) *object {
+	o := &object{values: map[string]interface{}{}}
+	for i := 0; i < len(kv); i += 2 {
+		o.set(kv[i].(string), kv[i+1])
+	}
+	return o
+}
+
+func (o *object) set(key string, value interface{}) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+func (o *object) get(key string) interface{} {
+	return o.values[key]
+}
+
+func (o *object) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		enc := json.NewEncoder(&b)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(k); err != nil {
+			return nil, err
+		}
+		b.WriteByte(':')
+		if err := enc.Encode(o.values[k]); err != nil {
+			return nil, err
+		}
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// writeYAML writes v, an *object or a []interface{}, as a YAML block, first
+// prefixing its first line and rest the others.
+func writeYAML(b *strings.Builder, v interface{}, first, rest string) {
+	switch v := v.(type) {
+	case *object:
+		for i, k := range v.keys {
+			pad := rest
+			if i == 0 {
+				pad = first
+			}
+			b.WriteString(pad + yamlScalar(k) + ":")
+			if isYAMLBlock(v.values[k]) {
+				b.WriteString("\n")
+				writeYAML(b, v.values[k], rest+"  ", rest+"  ")
+			} else if s, ok := v.values[k].(string); ok && isYAMLLiteral(s) {
+				// Multi-line descriptions read better as literal blocks.
+				b.WriteString(" |-\n")
+				for _, l := range strings.Split(s, "\n") {
+					if l != "" {
+						b.WriteString(rest + "  " + l)
+					}
+					b.WriteString("\n")
+				}
+			} else {
+				b.WriteString(" " + yamlScalar(v.values[k]) + "\n")
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			pad := rest
+			if i == 0 {
+				pad = first
+			}
+			b.WriteString(pad + "-")
+			if isYAMLBlock(item) {
+				writeYAML(b, item, " ", rest+"  ")
+			} else {
+				b.WriteString(" " + yamlScalar(item) + "\n")
+			}
+		}
+	}
+}
+
+// isYAMLBlock reports whether v is written as a block, not on one line.
+func isYAMLBlock(v interface{}) bool {
+	switch v := v.(type) {
+	case *object:
+		return v != nil && len(v.keys) > 0
+	case []interface{}:
+		return len(v) > 0
+	}
+	return false
+}
+
+// isYAMLLiteral reports whether s is written as a literal block: it has
+// several lines and the block keeps it as is.
+func isYAMLLiteral(s string) bool {
+	if !strings.Contains(s, "\n") || s != strings.TrimSpace(s) {
+		return false
+	}
+	for _, r := range s {
+		if r != '\n' && r != '\t' && (r < ' ' || r == 0x7f) {
+			return false
+		}
+	}
+	for _, l := range strings.Split(s, "\n") {
+		if l != strings.TrimRight(l, " \t") {
+			return false
+		}
+	}
+	return true
+}
+
+var yamlPlainRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_./-]*$`)
+
+// yamlScalar reports v written on one line, strings being quoted unless
+// YAML reads them back as the same string.
+func yamlScalar(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		switch strings.ToLower(v) {
+		case "true", "false", "yes", "no", "on", "off", "y", "n", "null":
+			return strconv.Quote(v)
+		}
+		if yamlPlainRe.MatchString(v) {
+			return v
+		}
+		var b bytes.Buffer
+		enc := json.NewEncoder(&b)
+		enc.SetEscapeHTML(false)
+		enc.Encode(v)
+		return strings.TrimSuffix(b.String(), "\n")
+	case bool:
+		return strconv.FormatBool(v)
+	case *object:
+		if v == nil {
+			return "null"
+		}
+		return "{}"
+	case []interface{}:
+		return "[]"
+	}
+	return fmt.Sprint(v)
+}
@@ -1,9 +1,333 @@
 package goapi
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// Options holds the plugin parameters, passed as a comma separated list of
+// key=value pairs through --go_api_opt or --go_api_out.
 type Options struct {
+	// DefaultBody is the body format used when HttpRule.body has no
+	// ",format" suffix. One of json, form or multi.
+	DefaultBody string
+
+	// Paths controls where the output files are placed, with the same
+	// meaning as protoc-gen-go: import or source_relative.
+	Paths string
+
+	// Module is stripped from the import path of every output file,
+	// like protoc-gen-go's module= parameter. Only valid with paths=import.
+	Module string
+
+	// PkgOverrides maps proto file names to Go import paths, given as
+	// M<file>=<import path> parameters. It takes precedence over go_package.
+	PkgOverrides map[string]string
+
+	// RawResponse exports a <Method>Raw variant of every method returning
+	// the undecoded *grequests.Response, for callers needing headers or status.
+	RawResponse bool
+
+	// Timeout is applied to every method call whose context has no deadline.
+	// Zero means no default timeout.
+	Timeout time.Duration
+
+	// MethodTimeouts overrides Timeout per method, keyed by Service.Method.
+	MethodTimeouts map[string]time.Duration
+
+	// ErrorType is the fully qualified proto message the body of non-2xx
+	// responses is decoded into, google.rpc.Status if empty.
+	ErrorType string
+
+	// Unannotated is what to do with methods without google.api.http:
+	// skip them, fail with an error, or post to /{package}.{Service}/{Method}.
+	Unannotated string
+
+	// Servers are the server backends generated next to the client,
+	// see serverBackends. None by default.
+	Servers []string
+
+	// Fake generates a Fake<Service> per service, serving the routes on
+	// an httptest.Server for tests.
+	Fake bool
+
+	// Mock generates a Mock<Service>Service per client interface,
+	// recording the calls and returning canned values, for tests.
+	Mock bool
+
+	// OpenAPI is the format of the OpenAPI 3.1 description generated next
+	// to the client, yaml or json. None if empty.
+	OpenAPI string
+
+	// OpenAPIOut is what a description covers: a proto file or a Go package.
+	OpenAPIOut string
+
+	// OmitDeprecated leaves the services and methods marked with
+	// option deprecated = true out of all the generated code.
+	OmitDeprecated bool
+
+	// Docs is the format of the API reference generated per service,
+	// only markdown for now. None if empty.
+	Docs string
+
+	// FormSeparator joins the field names of the keys of nested message
+	// fields in form and multipart bodies, e.g. "shelf.name" with ".".
+	FormSeparator string
+
+	// QueryStyle is how repeated fields are sent as query parameters:
+	// a=1&a=2 with repeat, a=1,2 with comma or a[]=1&a[]=2 with brackets.
+	QueryStyle string
 }
 
-func parseOptions(param *string) (*Options, error) {
+const (
+	pathsImport         = "import"
+	pathsSourceRelative = "source_relative"
+
+	unannotatedSkip  = "skip"
+	unannotatedError = "error"
+	unannotatedPost  = "post"
+
+	serverHTTP = "http"
+
+	openapiYAML = "yaml"
+	openapiJSON = "json"
+
+	openapiOutFile    = "file"
+	openapiOutPackage = "package"
+
+	docsMarkdown = "markdown"
+
+	queryRepeat   = "repeat"
+	queryComma    = "comma"
+	queryBrackets = "brackets"
+)
+
+// optionSetters maps every accepted parameter key to the function applying it.
+// Keys not listed here are rejected so a typo fails the build.
+var optionSetters = map[string]func(opts *Options, value string) error{
+	"body": func(opts *Options, value string) error {
+		switch value {
+		case bodyJSON, bodyFORM, bodyMULTI:
+			opts.DefaultBody = value
+			return nil
+		}
+		return fmt.Errorf("want one of %s, %s or %s", bodyJSON, bodyFORM, bodyMULTI)
+	},
+	"paths": func(opts *Options, value string) error {
+		switch value {
+		case pathsImport, pathsSourceRelative:
+			opts.Paths = value
+			return nil
+		}
+		return fmt.Errorf("want %s or %s", pathsImport, pathsSourceRelative)
+	},
+	"module": func(opts *Options, value string) error {
+		opts.Module = value
+		return nil
+	},
+	"raw_response": func(opts *Options, value string) (err error) {
+		opts.RawResponse, err = parseBool(value)
+		return err
+	},
+	// timeout=<duration> or timeout=<Service>.<Method>=<duration>
+	"timeout": func(opts *Options, value string) error {
+		meth := ""
+		if i := strings.IndexByte(value, '='); i >= 0 {
+			meth, value = value[:i], value[i+1:]
+			if strings.Count(meth, ".") != 1 {
+				return fmt.Errorf("want <Service>.<Method>=<duration>")
+			}
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		if d < 0 {
+			return fmt.Errorf("negative duration")
+		}
+		if meth == "" {
+			opts.Timeout = d
+		} else {
+			opts.MethodTimeouts[meth] = d
+		}
+		return nil
+	},
+	"unannotated": func(opts *Options, value string) error {
+		switch value {
+		case unannotatedSkip, unannotatedError, unannotatedPost:
+			opts.Unannotated = value
+			return nil
+		}
+		return fmt.Errorf("want one of %s, %s or %s", unannotatedSkip, unannotatedError, unannotatedPost)
+	},
+	"error_type": func(opts *Options, value string) error {
+		if value == "" {
+			return fmt.Errorf("want a message name, e.g. my.pkg.Error")
+		}
+		opts.ErrorType = "." + strings.TrimPrefix(value, ".")
+		return nil
+	},
+	"fake": func(opts *Options, value string) (err error) {
+		opts.Fake, err = parseBool(value)
+		return err
+	},
+	"mock": func(opts *Options, value string) (err error) {
+		opts.Mock, err = parseBool(value)
+		return err
+	},
+	"openapi": func(opts *Options, value string) error {
+		switch value {
+		case openapiYAML, openapiJSON:
+			opts.OpenAPI = value
+			return nil
+		}
+		return fmt.Errorf("want %s or %s", openapiYAML, openapiJSON)
+	},
+	"openapi_out": func(opts *Options, value string) error {
+		switch value {
+		case openapiOutFile, openapiOutPackage:
+			opts.OpenAPIOut = value
+			return nil
+		}
+		return fmt.Errorf("want %s or %s", openapiOutFile, openapiOutPackage)
+	},
+	"omit_deprecated": func(opts *Options, value string) (err error) {
+		opts.OmitDeprecated, err = parseBool(value)
+		return err
+	},
+	"docs": func(opts *Options, value string) error {
+		if value != docsMarkdown {
+			return fmt.Errorf("want %s", docsMarkdown)
+		}
+		opts.Docs = value
+		return nil
+	},
+	"form_separator": func(opts *Options, value string) error {
+		if value == "" {
+			return fmt.Errorf("want a separator, e.g. _")
+		}
+		opts.FormSeparator = value
+		return nil
+	},
+	"query_style": func(opts *Options, value string) error {
+		switch value {
+		case queryRepeat, queryComma, queryBrackets:
+			opts.QueryStyle = value
+			return nil
+		}
+		return fmt.Errorf("want one of %s, %s or %s", queryRepeat, queryComma, queryBrackets)
+	},
+	// server=<backend>, repeat it to generate several backends
+	"server": func(opts *Options, value string) error {
+		if value == "" {
+			value = serverHTTP
+		}
+		if lookupServerBackend(value) == nil {
+			var names []string
+			for _, b := range serverBackends {
+				names = append(names, b.Name)
+			}
+			return fmt.Errorf("want one of %s", strings.Join(names, ", "))
+		}
+		if !strContains(opts.Servers, value) {
+			opts.Servers = append(opts.Servers, value)
+		}
+		return nil
+	},
+}
+
+// methodTimeout reports the default timeout of the method meth of service
+// serv: the timeout parameter for the method, else (goapi.method).timeout,
+// else the timeout parameter.
+func (opts *Options) methodTimeout(serv string, meth *descriptor.MethodDescriptorProto) time.Duration {
+	if d, ok := opts.MethodTimeouts[serv+"."+meth.GetName()]; ok {
+		return d
+	}
+	if t := getMethodOptions(meth).GetTimeout(); t != nil {
+		return t.AsDuration()
+	}
+	return opts.Timeout
+}
+
+// omitService reports whether serv is left out because of omit_deprecated.
+func (opts *Options) omitService(serv *descriptor.ServiceDescriptorProto) bool {
+	return opts.OmitDeprecated && serv.GetOptions().GetDeprecated()
+}
+
+// omitMethod reports whether meth is left out because of omit_deprecated.
+func (opts *Options) omitMethod(meth *descriptor.MethodDescriptorProto) bool {
+	return opts.OmitDeprecated && meth.GetOptions().GetDeprecated()
+}
+
+// server reports whether the server side, the routes and the
+// <Service>HTTPServer interface, is generated.
+func (opts *Options) server() bool {
+	return len(opts.Servers) > 0 || opts.Fake
+}
+
+// backends reports the server backends to generate, in the order given.
+func (opts *Options) backends() []*serverBackend {
+	var bs []*serverBackend
+	for _, name := range opts.Servers {
+		bs = append(bs, lookupServerBackend(name))
+	}
+	return bs
+}
+
+// parseBool parses a boolean parameter, a bare key means true.
+func parseBool(value string) (bool, error) {
+	if value == "" {
+		return true, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+func defaultOptions() *Options {
+	return &Options{
+		DefaultBody:  bodyJSON,
+		Paths:        pathsImport,
+		Unannotated:  unannotatedSkip,
+		OpenAPIOut:   openapiOutFile,
+		PkgOverrides: map[string]string{},
+
+		FormSeparator: ".",
+		QueryStyle:    queryRepeat,
+
+		MethodTimeouts: map[string]time.Duration{},
+	}
+}
 
-	return nil, nil
+func parseOptions(param string) (*Options, error) {
+	opts := defaultOptions()
+	for _, p := range strings.Split(param, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		key, value := p, ""
+		if i := strings.IndexByte(p, '='); i >= 0 {
+			key, value = p[:i], p[i+1:]
+		}
+		// Only M<file>.proto is an import path override, so that keys like
+		// Mock fall through to the unknown parameter error.
+		if len(key) > len("M.proto") && strings.HasPrefix(key, "M") && strings.HasSuffix(key, ".proto") {
+			opts.PkgOverrides[key[1:]] = value
+			continue
+		}
+		set, ok := optionSetters[key]
+		if !ok {
+			return nil, fmt.Errorf("unknown parameter %q", key)
+		}
+		if err := set(opts, value); err != nil {
+			return nil, fmt.Errorf("invalid value %q for parameter %q: %v", value, key, err)
+		}
+	}
+	if opts.Module != "" && opts.Paths == pathsSourceRelative {
+		return nil, fmt.Errorf("cannot use module=%s with paths=%s", opts.Module, pathsSourceRelative)
+	}
+	return opts, nil
 }
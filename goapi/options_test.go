@@ -0,0 +1,87 @@
+package goapi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		param string
+		want  func(opts *Options)
+	}{
+		{"", func(opts *Options) {}},
+		{" , ", func(opts *Options) {}},
+		{"body=form", func(opts *Options) { opts.DefaultBody = bodyFORM }},
+		{"paths=source_relative", func(opts *Options) { opts.Paths = pathsSourceRelative }},
+		{"module=example.com/m", func(opts *Options) { opts.Module = "example.com/m" }},
+		{"raw_response", func(opts *Options) { opts.RawResponse = true }},
+		{"raw_response=false", func(opts *Options) { opts.RawResponse = false }},
+		{"timeout=10s", func(opts *Options) { opts.Timeout = 10 * time.Second }},
+		{"timeout=1s,timeout=Library.GetShelf=3s", func(opts *Options) {
+			opts.Timeout = time.Second
+			opts.MethodTimeouts["Library.GetShelf"] = 3 * time.Second
+		}},
+		{"unannotated=post", func(opts *Options) { opts.Unannotated = unannotatedPost }},
+		{"error_type=my.pkg.Error", func(opts *Options) { opts.ErrorType = ".my.pkg.Error" }},
+		{"server,server=http", func(opts *Options) { opts.Servers = []string{serverHTTP} }},
+		{"server,server=gin,server=gin", func(opts *Options) { opts.Servers = []string{serverHTTP, "gin"} }},
+		{"fake", func(opts *Options) { opts.Fake = true }},
+		{"mock", func(opts *Options) { opts.Mock = true }},
+		{"openapi=json,openapi_out=package", func(opts *Options) {
+			opts.OpenAPI, opts.OpenAPIOut = openapiJSON, openapiOutPackage
+		}},
+		{"docs=markdown", func(opts *Options) { opts.Docs = docsMarkdown }},
+		{"omit_deprecated", func(opts *Options) { opts.OmitDeprecated = true }},
+		{"form_separator=_", func(opts *Options) { opts.FormSeparator = "_" }},
+		{"query_style=comma", func(opts *Options) { opts.QueryStyle = queryComma }},
+		{"Mfoo/bar.proto=example.com/foo", func(opts *Options) {
+			opts.PkgOverrides["foo/bar.proto"] = "example.com/foo"
+		}},
+	}
+	for _, tt := range tests {
+		got, err := parseOptions(tt.param)
+		if err != nil {
+			t.Errorf("parseOptions(%q): %v", tt.param, err)
+			continue
+		}
+		want := defaultOptions()
+		tt.want(want)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("parseOptions(%q) = %+v, want %+v", tt.param, got, want)
+		}
+	}
+}
+
+func TestParseOptionsErrors(t *testing.T) {
+	tests := []struct {
+		param, err string
+	}{
+		{"bogus", `unknown parameter "bogus"`},
+		{"Mock=true", `unknown parameter "Mock"`},
+		{"Mode=x", `unknown parameter "Mode"`},
+		{"M.proto=x", `unknown parameter "M.proto"`},
+		{"body=xml", `invalid value "xml" for parameter "body"`},
+		{"paths=relative", `invalid value "relative" for parameter "paths"`},
+		{"raw_response=maybe", `invalid value "maybe" for parameter "raw_response"`},
+		{"timeout=soon", `invalid value "soon" for parameter "timeout"`},
+		{"timeout=-1s", `invalid value "-1s" for parameter "timeout"`},
+		{"timeout=GetShelf=1s", `invalid value "GetShelf=1s" for parameter "timeout"`},
+		{"unannotated=ignore", `invalid value "ignore" for parameter "unannotated"`},
+		{"error_type=", `invalid value "" for parameter "error_type"`},
+		{"server=grpc", `invalid value "grpc" for parameter "server"`},
+		{"openapi=toml", `invalid value "toml" for parameter "openapi"`},
+		{"docs=html", `invalid value "html" for parameter "docs"`},
+		{"form_separator=", `invalid value "" for parameter "form_separator"`},
+		{"query_style=pipe", `invalid value "pipe" for parameter "query_style"`},
+		{"module=m,paths=source_relative", "cannot use module=m with paths=source_relative"},
+	}
+	for _, tt := range tests {
+		_, err := parseOptions(tt.param)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("parseOptions(%q) error = %v, want %q", tt.param, err, tt.err)
+		}
+	}
+}
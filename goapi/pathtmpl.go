@@ -0,0 +1,370 @@
+package goapi
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// pathTemplate is a parsed google.api.http path template:
+//
+//	Template = "/" Segments [ Verb ] ;
+//	Segments = Segment { "/" Segment } ;
+//	Segment  = "*" | "**" | LITERAL | Variable ;
+//	Variable = "{" FieldPath [ "=" Segments ] "}" ;
+//	FieldPath = IDENT { "." IDENT } ;
+//	Verb     = ":" LITERAL ;
+//
+// Variables are flattened into the segments they match, so
+// "/v1/{name=shelves/*}:get" has the segments "v1", "shelves" and "*",
+// one variable covering segments [1, 3) and the verb "get".
+type pathTemplate struct {
+	segments  []string // literal, "*" or "**"
+	variables []*pathVariable
+	verb      string
+}
+
+// pathVariable is a variable of a pathTemplate.
+type pathVariable struct {
+	fieldPath  string // e.g. info.f_string
+	start, end int    // the variable matches segments[start:end]
+}
+
+// multiSegment reports whether the value of v may contain several path
+// segments, in which case '/' is kept as is when expanding it.
+func (v *pathVariable) multiSegment(t *pathTemplate) bool {
+	return v.end-v.start > 1 || t.segments[v.start] == "**"
+}
+
+// expand reports the fmt format and arguments building the path of t from
+// the request in, e.g. "/v1/%s:get" and goapiEscape(fmt.Sprint(in.GetName()), true).
+func (t *pathTemplate) expand() (string, []string, error) {
+	var format strings.Builder
+	var args []string
+	for i := 0; i < len(t.segments); {
+		format.WriteByte('/')
+		if v := t.variableAt(i); v != nil {
+			format.WriteString("%s")
+			args = append(args, fmt.Sprintf("goapiEscape(fmt.Sprint(in%s), %t)", fieldGetter(v.fieldPath), v.multiSegment(t)))
+			i = v.end
+			continue
+		}
+		seg := t.segments[i]
+		if seg == "*" || seg == "**" {
+			return "", nil, fmt.Errorf("can't expand %s outside of a variable", seg)
+		}
+		format.WriteString(strings.ReplaceAll(seg, "%", "%%"))
+		i++
+	}
+	if t.verb != "" {
+		format.WriteString(":" + strings.ReplaceAll(t.verb, "%", "%%"))
+	}
+	return format.String(), args, nil
+}
+
+// pattern reports the regular expression matching the escaped paths t
+// matches, with a group per variable, e.g. `^/v1/(shelves/[^/]+):get$`.
+func (t *pathTemplate) pattern() string {
+	var b strings.Builder
+	b.WriteByte('^')
+	for i, seg := range t.segments {
+		b.WriteByte('/')
+		for _, v := range t.variables {
+			if v.start == i {
+				b.WriteByte('(')
+			}
+		}
+		b.WriteString(segmentPattern(seg))
+		for _, v := range t.variables {
+			if v.end == i+1 {
+				b.WriteByte(')')
+			}
+		}
+	}
+	if t.verb != "" {
+		b.WriteString(regexp.QuoteMeta(":" + t.verb))
+	}
+	b.WriteByte('$')
+	return b.String()
+}
+
+// variablePattern reports the regular expression matching the value of v,
+// e.g. `^shelves/[^/]+$`, empty if v is a single "*" segment.
+func (t *pathTemplate) variablePattern(v *pathVariable) string {
+	segs := t.segments[v.start:v.end]
+	if len(segs) == 1 && segs[0] == "*" {
+		return ""
+	}
+	pats := make([]string, len(segs))
+	for i, seg := range segs {
+		pats[i] = segmentPattern(seg)
+	}
+	return "^" + strings.Join(pats, "/") + "$"
+}
+
+// segmentPattern reports the regular expression matching the segment seg.
+func segmentPattern(seg string) string {
+	switch seg {
+	case "*":
+		return "[^/]+"
+	case "**":
+		return ".*"
+	}
+	return regexp.QuoteMeta(seg)
+}
+
+// openapiParam is a path parameter of an OpenAPI path, see openapiPath.
+type openapiParam struct {
+	name string
+	v    *pathVariable // the variable the parameter is a segment of
+	seg  int           // index of the segment in the template
+}
+
+// whole reports whether p stands for the whole value of its variable.
+func (p *openapiParam) whole() bool {
+	return p.v.end-p.v.start == 1
+}
+
+// openapiPath reports t as an OpenAPI path and its parameters. Literal
+// segments are kept, those of the variables too, and every wildcard is a
+// parameter: "/v1/{name=shelves/*/books/*}:get" is
+// "/v1/shelves/{shelf}/books/{book}:get". A wildcard following a literal
+// of its variable is named after the literal, any other after the field
+// path of the variable, e.g. "/v1/{shelf}".
+func (t *pathTemplate) openapiPath() (string, []*openapiParam, error) {
+	var b strings.Builder
+	var params []*openapiParam
+	used := map[string]bool{}
+	for i, seg := range t.segments {
+		b.WriteByte('/')
+		if seg != "*" && seg != "**" {
+			b.WriteString(seg)
+			continue
+		}
+		v := t.variableContaining(i)
+		if v == nil {
+			return "", nil, fmt.Errorf("can't describe %s outside of a variable", seg)
+		}
+		name := v.fieldPath
+		if prev := i - 1; prev >= v.start && t.segments[prev] != "*" && t.segments[prev] != "**" {
+			name = singular(t.segments[prev])
+		}
+		for n, base := 2, name; used[name]; n++ {
+			name = fmt.Sprintf("%s%d", base, n)
+		}
+		used[name] = true
+		b.WriteString("{" + name + "}")
+		params = append(params, &openapiParam{name: name, v: v, seg: i})
+	}
+	if t.verb != "" {
+		b.WriteString(":" + t.verb)
+	}
+	return b.String(), params, nil
+}
+
+// singular reports the singular of the English plural noun s, if it is
+// one, e.g. "shelf" for "shelves" and "book" for "books".
+func singular(s string) string {
+	switch {
+	case strings.HasSuffix(s, "ies") && len(s) > 3:
+		return s[:len(s)-3] + "y"
+	case strings.HasSuffix(s, "ves") && len(s) > 3:
+		return s[:len(s)-3] + "f"
+	case strings.HasSuffix(s, "ss"), strings.HasSuffix(s, "us"), !strings.HasSuffix(s, "s"), len(s) == 1:
+		return s
+	}
+	return s[:len(s)-1]
+}
+
+// variableTemplate reports the segments v matches, e.g. "shelves/*".
+func (t *pathTemplate) variableTemplate(v *pathVariable) string {
+	return strings.Join(t.segments[v.start:v.end], "/")
+}
+
+// prefix reports the literal leading segments of t as a ServeMux pattern:
+// the exact path if t has no wildcard, else the subtree below the last
+// literal segment, e.g. "/v1/shelves/" for "/v1/{name=shelves/*}".
+func (t *pathTemplate) prefix() string {
+	var b strings.Builder
+	for _, seg := range t.segments {
+		b.WriteByte('/')
+		if seg == "*" || seg == "**" {
+			return b.String()
+		}
+		b.WriteString(seg)
+	}
+	if t.verb != "" {
+		b.WriteString(":" + t.verb)
+	}
+	return b.String()
+}
+
+// routerSegments reports the segments of t for routers with path
+// parameters, such as gin: literals are kept, "*" and "**" stand for
+// parameters. Routers can't match a custom verb, so with one the last
+// segment is a "*" too and the pattern tells the routes apart.
+func (t *pathTemplate) routerSegments() []string {
+	segs := make([]string, len(t.segments))
+	copy(segs, t.segments)
+	if last := len(segs) - 1; t.verb != "" && segs[last] != "**" {
+		segs[last] = "*"
+	}
+	return segs
+}
+
+// variableContaining reports the variable matching segment i, nil if none.
+func (t *pathTemplate) variableContaining(i int) *pathVariable {
+	for _, v := range t.variables {
+		if v.start <= i && i < v.end {
+			return v
+		}
+	}
+	return nil
+}
+
+// variableAt reports the variable starting at segment i, nil if none.
+func (t *pathTemplate) variableAt(i int) *pathVariable {
+	for _, v := range t.variables {
+		if v.start == i {
+			return v
+		}
+	}
+	return nil
+}
+
+// parsePathTemplate parses s, rejecting malformed templates.
+func parsePathTemplate(s string) (*pathTemplate, error) {
+	p := &templateParser{src: s}
+	t, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("invalid path template %q: %v", s, err)
+	}
+	return t, nil
+}
+
+type templateParser struct {
+	src string
+	pos int
+	t   pathTemplate
+}
+
+func (p *templateParser) parse() (*pathTemplate, error) {
+	if !strings.HasPrefix(p.src, "/") {
+		return nil, fmt.Errorf("must start with /")
+	}
+	p.pos++
+	if err := p.parseSegments(nil); err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.src) && p.src[p.pos] == ':' {
+		p.pos++
+		p.t.verb = p.readLiteral()
+		if p.t.verb == "" {
+			return nil, fmt.Errorf("empty verb at %d", p.pos)
+		}
+	}
+	if p.pos < len(p.src) {
+		return nil, fmt.Errorf("unexpected %q at %d", p.src[p.pos], p.pos)
+	}
+
+	for i, seg := range p.t.segments {
+		if seg == "**" && i != len(p.t.segments)-1 {
+			return nil, fmt.Errorf("** must be the last segment")
+		}
+	}
+	return &p.t, nil
+}
+
+// parseSegments parses Segments, v is the enclosing variable if any.
+func (p *templateParser) parseSegments(v *pathVariable) error {
+	for {
+		if err := p.parseSegment(v); err != nil {
+			return err
+		}
+		if p.pos >= len(p.src) || p.src[p.pos] != '/' {
+			return nil
+		}
+		p.pos++
+	}
+}
+
+func (p *templateParser) parseSegment(v *pathVariable) error {
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], "**"):
+		p.pos += 2
+		p.t.segments = append(p.t.segments, "**")
+	case strings.HasPrefix(p.src[p.pos:], "*"):
+		p.pos++
+		p.t.segments = append(p.t.segments, "*")
+	case strings.HasPrefix(p.src[p.pos:], "{"):
+		if v != nil {
+			return fmt.Errorf("nested variable at %d", p.pos)
+		}
+		return p.parseVariable()
+	default:
+		lit := p.readLiteral()
+		if lit == "" {
+			return fmt.Errorf("empty segment at %d", p.pos)
+		}
+		p.t.segments = append(p.t.segments, lit)
+	}
+	return nil
+}
+
+func (p *templateParser) parseVariable() error {
+	p.pos++ // {
+	end := strings.IndexAny(p.src[p.pos:], "=}")
+	if end < 0 {
+		return fmt.Errorf("unclosed variable at %d", p.pos)
+	}
+	v := &pathVariable{
+		fieldPath: p.src[p.pos : p.pos+end],
+		start:     len(p.t.segments),
+	}
+	for _, ident := range strings.Split(v.fieldPath, ".") {
+		if !isIdent(ident) {
+			return fmt.Errorf("invalid field path %q", v.fieldPath)
+		}
+	}
+	p.pos += end
+	if p.src[p.pos] == '=' {
+		p.pos++
+		if err := p.parseSegments(v); err != nil {
+			return err
+		}
+	} else {
+		// {var} is a shorthand for {var=*}.
+		p.t.segments = append(p.t.segments, "*")
+	}
+	if p.pos >= len(p.src) || p.src[p.pos] != '}' {
+		return fmt.Errorf("unclosed variable %q", v.fieldPath)
+	}
+	p.pos++
+	v.end = len(p.t.segments)
+	p.t.variables = append(p.t.variables, v)
+	return nil
+}
+
+// readLiteral reads up to the next character that can't be part of a literal.
+func (p *templateParser) readLiteral() string {
+	start := p.pos
+	for p.pos < len(p.src) && !strings.ContainsRune("/{}*=:", rune(p.src[p.pos])) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func isIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
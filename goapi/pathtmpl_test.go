@@ -0,0 +1,185 @@
+package goapi
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestParsePathTemplate(t *testing.T) {
+	tests := []struct {
+		src      string
+		segments []string
+		vars     []pathVariable
+		verb     string
+	}{
+		{"/v1/shelves", []string{"v1", "shelves"}, nil, ""},
+		{"/v1/{name}", []string{"v1", "*"}, []pathVariable{{"name", 1, 2}}, ""},
+		{"/v1/{name=shelves/*}:get", []string{"v1", "shelves", "*"}, []pathVariable{{"name", 1, 3}}, "get"},
+		{"/v1/{name=shelves/*/books/*}", []string{"v1", "shelves", "*", "books", "*"}, []pathVariable{{"name", 1, 5}}, ""},
+		{"/v1/shelves/{shelf}/books/{book.id}", []string{"v1", "shelves", "*", "books", "*"},
+			[]pathVariable{{"shelf", 2, 3}, {"book.id", 4, 5}}, ""},
+		{"/v1/{path=files/**}", []string{"v1", "files", "**"}, []pathVariable{{"path", 1, 3}}, ""},
+		{"/v1/*:batchGet", []string{"v1", "*"}, nil, "batchGet"},
+	}
+	for _, tt := range tests {
+		got, err := parsePathTemplate(tt.src)
+		if err != nil {
+			t.Errorf("parsePathTemplate(%q): %v", tt.src, err)
+			continue
+		}
+		var vars []pathVariable
+		for _, v := range got.variables {
+			vars = append(vars, *v)
+		}
+		if !reflect.DeepEqual(got.segments, tt.segments) || !reflect.DeepEqual(vars, tt.vars) || got.verb != tt.verb {
+			t.Errorf("parsePathTemplate(%q) = %q %v %q, want %q %v %q", tt.src, got.segments, vars, got.verb, tt.segments, tt.vars, tt.verb)
+		}
+	}
+}
+
+func TestParsePathTemplateErrors(t *testing.T) {
+	tests := []struct {
+		src, err string
+	}{
+		{"v1/shelves", "must start with /"},
+		{"/v1//shelves", "empty segment at 4"},
+		{"/v1/{name", "unclosed variable at 5"},
+		{"/v1/{name=shelves/*", `unclosed variable "name"`},
+		{"/v1/{na-me}", `invalid field path "na-me"`},
+		{"/v1/{a.}", `invalid field path "a."`},
+		{"/v1/{name={id}}", "nested variable at 10"},
+		{"/v1/{name=**}/books", "** must be the last segment"},
+		{"/v1/shelves:", "empty verb at 12"},
+		{"/v1/shelves}", `unexpected '}' at 11`},
+	}
+	for _, tt := range tests {
+		_, err := parsePathTemplate(tt.src)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("parsePathTemplate(%q) error = %v, want %q", tt.src, err, tt.err)
+		}
+	}
+}
+
+func TestPathTemplateExpand(t *testing.T) {
+	tests := []struct {
+		src, format string
+		args        []string
+	}{
+		{"/v1/shelves", "/v1/shelves", nil},
+		{"/v1/{name=shelves/*}:get", "/v1/%s:get", []string{"goapiEscape(fmt.Sprint(in.GetName()), true)"}},
+		{"/v1/shelves/{shelf}/books/{book.id}", "/v1/shelves/%s/books/%s", []string{
+			"goapiEscape(fmt.Sprint(in.GetShelf()), false)",
+			"goapiEscape(fmt.Sprint(in.GetBook().GetId()), false)",
+		}},
+		{"/v1/{path=**}", "/v1/%s", []string{"goapiEscape(fmt.Sprint(in.GetPath()), true)"}},
+		{"/v1/100%", "/v1/100%%", nil},
+	}
+	for _, tt := range tests {
+		format, args, err := mustParsePathTemplate(t, tt.src).expand()
+		if err != nil || format != tt.format || !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("expand(%q) = %q %q %v, want %q %q", tt.src, format, args, err, tt.format, tt.args)
+		}
+	}
+	if _, _, err := mustParsePathTemplate(t, "/v1/*").expand(); err == nil {
+		t.Errorf("expand(%q) succeeded, want an error", "/v1/*")
+	}
+}
+
+func TestPathTemplatePattern(t *testing.T) {
+	tests := []struct {
+		src, pattern string
+		match        []string
+		noMatch      []string
+	}{
+		{"/v1/{name=shelves/*}:get", `^/v1/(shelves/[^/]+):get$`,
+			[]string{"/v1/shelves/1:get"}, []string{"/v1/shelves/1", "/v1/shelves/1/books/2:get"}},
+		{"/v1/shelves/{shelf}", `^/v1/shelves/([^/]+)$`,
+			[]string{"/v1/shelves/a%2Fb"}, []string{"/v1/shelves/", "/v1/shelves/a/b"}},
+		{"/v1/{path=files/**}", `^/v1/(files/.*)$`,
+			[]string{"/v1/files/a/b/c"}, []string{"/v1/dirs/a"}},
+		{"/v1.0/shelves", `^/v1\.0/shelves$`,
+			[]string{"/v1.0/shelves"}, []string{"/v1x0/shelves"}},
+	}
+	for _, tt := range tests {
+		tmpl := mustParsePathTemplate(t, tt.src)
+		if got := tmpl.pattern(); got != tt.pattern {
+			t.Errorf("pattern(%q) = %q, want %q", tt.src, got, tt.pattern)
+			continue
+		}
+		re := regexp.MustCompile(tt.pattern)
+		for _, p := range tt.match {
+			if !re.MatchString(p) {
+				t.Errorf("pattern(%q) doesn't match %q", tt.src, p)
+			}
+		}
+		for _, p := range tt.noMatch {
+			if re.MatchString(p) {
+				t.Errorf("pattern(%q) matches %q", tt.src, p)
+			}
+		}
+	}
+}
+
+func TestPathTemplateOpenAPIPath(t *testing.T) {
+	tests := []struct {
+		src, path string
+		params    []string // name:field path
+	}{
+		{"/v1/shelves", "/v1/shelves", nil},
+		{"/v1/{shelf}", "/v1/{shelf}", []string{"shelf:shelf"}},
+		{"/v1/{name=shelves/*}", "/v1/shelves/{shelf}", []string{"shelf:name"}},
+		{"/v1/{name=shelves/*/books/*}:get", "/v1/shelves/{shelf}/books/{book}:get", []string{"shelf:name", "book:name"}},
+		{"/v1/{parent=libraries/*}/{name=media/*}", "/v1/libraries/{library}/media/{media}", []string{"library:parent", "media:name"}},
+		{"/v1/{book.name=*/*}", "/v1/{book.name}/{book.name2}", []string{"book.name:book.name", "book.name2:book.name"}},
+		{"/v1/{a=x/*}/{b=x/*}", "/v1/x/{x}/x/{x2}", []string{"x:a", "x2:b"}},
+		{"/v1/{path=files/**}", "/v1/files/{file}", []string{"file:path"}},
+		{"/v1/{path=**}", "/v1/{path}", []string{"path:path"}},
+		{"/v1/{name=status/*}", "/v1/status/{status}", []string{"status:name"}},
+	}
+	for _, tt := range tests {
+		got, params, err := mustParsePathTemplate(t, tt.src).openapiPath()
+		var names []string
+		for _, p := range params {
+			names = append(names, p.name+":"+p.v.fieldPath)
+		}
+		if err != nil || got != tt.path || !reflect.DeepEqual(names, tt.params) {
+			t.Errorf("openapiPath(%q) = %q %q %v, want %q %q", tt.src, got, names, err, tt.path, tt.params)
+		}
+	}
+	if _, _, err := mustParsePathTemplate(t, "/v1/*").openapiPath(); err == nil {
+		t.Errorf("openapiPath(%q) succeeded, want an error", "/v1/*")
+	}
+}
+
+func TestPathTemplateRouting(t *testing.T) {
+	tests := []struct {
+		src, prefix string
+		segments    []string
+	}{
+		{"/v1/shelves", "/v1/shelves", []string{"v1", "shelves"}},
+		{"/v1/shelves:batchGet", "/v1/shelves:batchGet", []string{"v1", "*"}},
+		{"/v1/{name=shelves/*}", "/v1/shelves/", []string{"v1", "shelves", "*"}},
+		{"/v1/{name=shelves/*}:cancel", "/v1/shelves/", []string{"v1", "shelves", "*"}},
+		{"/v1/{path=**}:get", "/v1/", []string{"v1", "**"}},
+	}
+	for _, tt := range tests {
+		tmpl := mustParsePathTemplate(t, tt.src)
+		if got := tmpl.prefix(); got != tt.prefix {
+			t.Errorf("prefix(%q) = %q, want %q", tt.src, got, tt.prefix)
+		}
+		if got := tmpl.routerSegments(); !reflect.DeepEqual(got, tt.segments) {
+			t.Errorf("routerSegments(%q) = %q, want %q", tt.src, got, tt.segments)
+		}
+	}
+}
+
+func mustParsePathTemplate(t *testing.T, src string) *pathTemplate {
+	t.Helper()
+	tmpl, err := parsePathTemplate(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tmpl
+}
@@ -3,7 +3,6 @@ package goapi
 import (
 	"fmt"
 	"net/http"
-	"regexp"
 	"sort"
 	"strings"
 	"unicode"
@@ -11,13 +10,12 @@ import (
 
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+	goapipb "github.com/open-api-go/protoc-gen-go_api/goapi/annotations"
 	"github.com/open-api-go/protoc-gen-go_api/pbinfo"
 	"google.golang.org/genproto/googleapis/api/annotations"
 	"google.golang.org/protobuf/proto"
 )
 
-var httpPatternVarRegex = regexp.MustCompile(`{([a-zA-Z0-9_.]+?)(=[^{}]+)?}`)
-
 var (
 	descInfo pbinfo.Info
 )
@@ -28,6 +26,7 @@ const (
 	emptyType               = "." + emptyValue
 	lroType                 = ".google.longrunning.Operation"
 	httpBodyType            = ".google.api.HttpBody"
+	statusPkg               = "google.golang.org/genproto/googleapis/rpc/status"
 	alpha                   = "alpha"
 	beta                    = "beta"
 	disableDeadlinesVar     = "GOOGLE_API_GO_EXPERIMENTAL_DISABLE_DEFAULT_DEADLINE"
@@ -61,33 +60,85 @@ var wellKnownTypes = []string{
 	".google.protobuf.ListValue",
 }
 
+// sessionVerbs are the HTTP verbs grequests.Session has a method for,
+// the only ones a generated client can send.
+var sessionVerbs = []string{
+	http.MethodGet,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodHead,
+	http.MethodOptions,
+}
+
 type httpInfo struct {
-	verb, url, body, format string
+	verb, url, body, format, responseBody string
+
+	// legacyFormat is set when format comes from the deprecated
+	// "field,format" syntax of the body.
+	legacyFormat bool
+
+	// bindings are the additional_bindings of the rule.
+	bindings []*httpInfo
+}
+
+// bodyEncodings maps (goapi.method).body_encoding to the body formats.
+var bodyEncodings = map[goapipb.BodyEncoding]string{
+	goapipb.BodyEncoding_BODY_ENCODING_JSON:      bodyJSON,
+	goapipb.BodyEncoding_BODY_ENCODING_FORM:      bodyFORM,
+	goapipb.BodyEncoding_BODY_ENCODING_MULTIPART: bodyMULTI,
 }
 
-func initRest(req *plugin.CodeGeneratorRequest) {
+// authSchemes maps (goapi.method).auth schemes to the names the runtime
+// authenticates requests by, see goapiClientConfig.authenticate.
+var authSchemes = map[goapipb.Auth_Scheme]string{
+	goapipb.Auth_SCHEME_BEARER:  "bearer",
+	goapipb.Auth_SCHEME_BASIC:   "basic",
+	goapipb.Auth_SCHEME_API_KEY: "api_key",
+}
+
+func initRest(req *plugin.CodeGeneratorRequest, opts *Options) {
 	descInfo = pbinfo.Of(req.GetProtoFile())
+	for f, pkg := range opts.PkgOverrides {
+		descInfo.PkgOverrides[f] = pkg
+	}
 }
 
-func genRestMethodCode(fd *descriptor.FileDescriptorProto, serv *descriptor.ServiceDescriptorProto, meth *descriptor.MethodDescriptorProto) (string, error) {
+func genRestMethodCode(fd *descriptor.FileDescriptorProto, serv *descriptor.ServiceDescriptorProto, meth *descriptor.MethodDescriptorProto, httpInfo *httpInfo, opts *Options) (string, error) {
 	code := strings.Builder{}
 
-	httpInfo := getHTTPInfo(meth)
-	// 处理path和params里面带有{xxx}的字段。但是gin的路由是:xxx形式，到时候可能需要转一下才行
-	ps := baseURL(httpInfo)
-	fmtStr := httpInfo.url
-	// TODO(noahdietz): handle more complex path urls involving = and *,
-	// e.g. v1beta1/repeat/{info.f_string=first/*}/{info.f_child.f_string=second/**}:pathtrailingresource
-	fmtStr = httpPatternVarRegex.ReplaceAllStringFunc(fmtStr, func(s string) string { return "%v" })
+	verb := strings.ToUpper(httpInfo.verb)
+	if verb == "" {
+		return "", fmt.Errorf("method %q: google.api.http has no HTTP verb, set one of get, post, put, patch, delete or custom", meth.GetName())
+	}
+	if !strContains(sessionVerbs, verb) {
+		return "", fmt.Errorf("method %q: custom HTTP verb %q is not supported, the client can only send %s", meth.GetName(), verb, strings.Join(sessionVerbs, ", "))
+	}
+
+	// 处理path和params里面带有{xxx}的字段
+	tmpl, err := parsePathTemplate(httpInfo.url)
+	if err != nil {
+		return "", fmt.Errorf("method %q: %v", meth.GetName(), err)
+	}
+	for _, v := range tmpl.variables {
+		if err := checkPathVariable(meth, v.fieldPath); err != nil {
+			return "", err
+		}
+	}
+	fmtStr, ps, err := tmpl.expand()
+	if err != nil {
+		return "", fmt.Errorf("method %q: path template %q: %v", meth.GetName(), httpInfo.url, err)
+	}
 	rawURL := fmt.Sprintf("%s%s", "%s", fmtStr)
 	if len(ps) > 0 {
-		code.WriteString(fmt.Sprintf("rawURL := fmt.Sprintf(%q, c.addr, %s)\n", rawURL, strings.Join(ps, ",")))
+		code.WriteString(fmt.Sprintf("rawURL := fmt.Sprintf(%q, c.addr, %s)\n", rawURL, strings.Join(ps, ", ")))
 	} else {
 		code.WriteString(fmt.Sprintf("rawURL := fmt.Sprintf(%q, c.addr)\n", rawURL))
 	}
 
 	// 还有一些，没有写在uri里面的，从结构体里面解析
-	query := queryString(meth)
+	query := queryString(meth, httpInfo, opts.QueryStyle)
 	if len(query) > 0 {
 		param, err := getQueryStringContent(strings.Join(query, "\n\t"))
 		if err != nil {
@@ -95,14 +146,40 @@ func genRestMethodCode(fd *descriptor.FileDescriptorProto, serv *descriptor.Serv
 		}
 		code.WriteString(param)
 	}
+	mo := getMethodOptions(meth)
+	if hs := mo.GetHeaders(); len(hs) > 0 {
+		// Before the options of the call, which may override them.
+		var names []string
+		for name := range hs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var headers []string
+		for _, name := range names {
+			headers = append(headers, fmt.Sprintf("%q: %q,", name, hs[name]))
+		}
+		code.WriteString(fmt.Sprintf("\topts = append([]grequests.RequestOption{grequests.AddHeaders(map[string]string{\n\t\t%s\n\t})}, opts...)\n", strings.Join(headers, "\n\t\t")))
+	}
+	if auth := mo.GetAuth(); auth.GetScheme() != goapipb.Auth_SCHEME_UNSPECIFIED {
+		if auth.GetScheme() == goapipb.Auth_SCHEME_API_KEY && auth.GetName() == "" {
+			return "", fmt.Errorf("method %q: (goapi.method).auth needs a name for SCHEME_API_KEY", meth.GetName())
+		}
+		code.WriteString(fmt.Sprintf("\trawURL, authOpts, err := c.config.authenticate(ctx, rawURL, %q, %q, %t)\n", authSchemes[auth.GetScheme()], auth.GetName(), auth.GetInQuery()))
+		code.WriteString("\tif err != nil {\n")
+		code.WriteString("\t\treturn nil, err\n")
+		code.WriteString("\t}\n")
+		code.WriteString("\topts = append(authOpts, opts...)\n")
+	}
 	// 处理body
 	body := "nil"
 	format := bodyJSON
-	verb := strings.ToUpper(httpInfo.verb)
 	if httpInfo.body != "" {
 		format = httpInfo.format
-		if verb == http.MethodGet || verb == http.MethodDelete {
-			return "", fmt.Errorf("invalid use of body parameter for a get/delete method %q", meth.GetName())
+		if format == "" {
+			format = opts.DefaultBody
+		}
+		if verb == http.MethodGet || verb == http.MethodDelete || verb == http.MethodHead {
+			return "", fmt.Errorf("invalid use of body parameter for a get/delete/head method %q", meth.GetName())
 		}
 		body = "in"
 		if httpInfo.body != "*" {
@@ -112,7 +189,7 @@ func genRestMethodCode(fd *descriptor.FileDescriptorProto, serv *descriptor.Serv
 	if body != "nil" {
 		switch format {
 		case bodyFORM:
-			forms := bodyForm(meth, httpInfo)
+			forms := bodyForm(meth, httpInfo, opts.FormSeparator)
 			if len(forms) > 0 {
 				form, err := getBodyFormContent(strings.Join(forms, "\n\t"))
 				if err != nil {
@@ -121,31 +198,97 @@ func genRestMethodCode(fd *descriptor.FileDescriptorProto, serv *descriptor.Serv
 				code.WriteString(form)
 			}
 		case bodyMULTI:
-			forms := bodyForm(meth, httpInfo)
-			if len(forms) > 0 {
-				form, err := getMultipartContent(strings.Join(forms, "\n\t"))
-				if err != nil {
-					return "", err
-				}
-				code.WriteString(form)
+			form, err := multipartBody(meth, httpInfo, opts.FormSeparator)
+			if err != nil {
+				return "", err
 			}
+			code.WriteString(form)
 		default:
+			if httpInfo.body == "*" || lookupField(meth.GetInputType(), httpInfo.body).GetType() == fieldTypeMessage {
+				// Messages are encoded with protojson, like the server decodes them.
+				code.WriteString(fmt.Sprintf("\tbs, err := protojson.Marshal(%s)\n", body))
+				code.WriteString("\tif err != nil {\n")
+				code.WriteString("\t\treturn nil, err\n")
+				code.WriteString("\t}\n")
+				body = "json.RawMessage(bs)"
+			}
 			code.WriteString(fmt.Sprintf("\topts = append(opts, grequests.JSON(%s))\n", body))
 		}
 	}
+	code.WriteString("\topts = append(opts, grequests.Context(ctx))\n")
 	code.WriteString(fmt.Sprintf("\treturn c.session.%s(rawURL,opts...)", upperFirst(httpInfo.verb)))
 	return code.String(), nil
 }
 
+// genRestResponseCode generates the code decoding resp into resTyp,
+// the Go type of the method output.
+func genRestResponseCode(meth *descriptor.MethodDescriptorProto, info *httpInfo, resTyp string) (string, error) {
+	code := strings.Builder{}
+	if meth.GetOutputType() == httpBodyType {
+		// HttpBody carries the raw response as is.
+		code.WriteString(fmt.Sprintf("return &%s{ContentType: resp.Header.Get(%q), Data: resp.Bytes()}, nil", resTyp, "Content-Type"))
+		return code.String(), nil
+	}
+
+	code.WriteString(fmt.Sprintf("out := new(%s)\n", resTyp))
+	code.WriteString("\tbody := resp.Bytes()\n")
+	code.WriteString("\tif len(body) == 0 {\n")
+	code.WriteString("\t\treturn out, nil\n")
+	code.WriteString("\t}\n")
+	if info != nil && info.responseBody != "" {
+		// response_body names a top level field, the body is that field only.
+		field := lookupField(meth.GetOutputType(), info.responseBody)
+		if field == nil || strings.Contains(info.responseBody, ".") {
+			return "", fmt.Errorf("response_body %q of method %q is not a field of %s", info.responseBody, meth.GetName(), meth.GetOutputType())
+		}
+		code.WriteString(fmt.Sprintf("\tbody = append(append([]byte(%q), body...), '}')\n", fmt.Sprintf("{%q:", field.GetJsonName())))
+	}
+	if getMethodOptions(meth).GetResponseEncoding() == goapipb.ResponseEncoding_RESPONSE_ENCODING_JSON_STRICT {
+		code.WriteString("\tif err := protojson.Unmarshal(body, out); err != nil {\n")
+	} else {
+		code.WriteString("\tif err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(body, out); err != nil {\n")
+	}
+	code.WriteString("\t\treturn nil, err\n")
+	code.WriteString("\t}\n")
+	code.WriteString("\treturn out, nil")
+	return code.String(), nil
+}
+
 func getHTTPInfo(m *descriptor.MethodDescriptorProto) *httpInfo {
-	if m == nil || m.GetOptions() == nil {
+	if m == nil || !proto.HasExtension(m.GetOptions(), annotations.E_Http) {
 		return nil
 	}
 
 	eHTTP := proto.GetExtension(m.GetOptions(), annotations.E_Http)
 
 	httpRule := eHTTP.(*annotations.HttpRule)
-	info := httpInfo{}
+	info := parseHTTPRule(httpRule)
+	for _, binding := range httpRule.GetAdditionalBindings() {
+		info.bindings = append(info.bindings, parseHTTPRule(binding))
+	}
+	// (goapi.method).body_encoding takes precedence over the comma syntax.
+	if format, ok := bodyEncodings[getMethodOptions(m).GetBodyEncoding()]; ok {
+		for _, rule := range append([]*httpInfo{info}, info.bindings...) {
+			if rule.body != "" {
+				rule.format = format
+			}
+		}
+	}
+	return info
+}
+
+// getMethodOptions reports the (goapi.method) options of m, nil if none.
+func getMethodOptions(m *descriptor.MethodDescriptorProto) *goapipb.MethodOptions {
+	if m.GetOptions() == nil || !proto.HasExtension(m.GetOptions(), goapipb.E_Method) {
+		return nil
+	}
+	return proto.GetExtension(m.GetOptions(), goapipb.E_Method).(*goapipb.MethodOptions)
+}
+
+func parseHTTPRule(httpRule *annotations.HttpRule) *httpInfo {
+	info := httpInfo{
+		responseBody: httpRule.GetResponseBody(),
+	}
 	body := httpRule.GetBody()
 	if len(body) == 0 {
 		info.body = ""
@@ -153,10 +296,10 @@ func getHTTPInfo(m *descriptor.MethodDescriptorProto) *httpInfo {
 	bs := strings.Split(body, ",")
 	if len(bs) == 1 {
 		info.body = body
-		info.format = "json"
 	} else {
 		info.body = bs[0]
 		info.format = bs[1]
+		info.legacyFormat = true
 	}
 
 	switch httpRule.GetPattern().(type) {
@@ -175,52 +318,200 @@ func getHTTPInfo(m *descriptor.MethodDescriptorProto) *httpInfo {
 	case *annotations.HttpRule_Delete:
 		info.verb = "delete"
 		info.url = httpRule.GetDelete()
+	case *annotations.HttpRule_Custom:
+		info.verb = strings.ToLower(httpRule.GetCustom().GetKind())
+		info.url = httpRule.GetCustom().GetPath()
 	}
 
 	return &info
 }
 
-func baseURL(info *httpInfo) []string {
-	tokens := []string{}
-	// Can't just reuse pathParams because the order matters
-	for _, path := range httpPatternVarRegex.FindAllStringSubmatch(info.url, -1) {
-		// In the returned slice, the zeroth element is the full regex match,
-		// and the subsequent elements are the sub group matches.
-		// See the docs for FindStringSubmatch for further details.
-		tokens = append(tokens, fmt.Sprintf("in%s", fieldGetter(path[1])))
+// checkPathVariable reports an error if the field path of a path variable
+// doesn't name a singular, non-message field of the request of m.
+func checkPathVariable(m *descriptor.MethodDescriptorProto, fieldPath string) error {
+	field := lookupField(m.GetInputType(), fieldPath)
+	segs := strings.Split(fieldPath, ".")
+	if field == nil || field.GetName() != segs[len(segs)-1] {
+		return fmt.Errorf("method %q: path variable %q is not a field of %s", m.GetName(), fieldPath, m.GetInputType())
+	}
+	if field.GetLabel() == fieldLabelRepeated || field.GetType() == fieldTypeMessage {
+		return fmt.Errorf("method %q: path variable %q must be a singular primitive field", m.GetName(), fieldPath)
 	}
-	return tokens
+	return nil
 }
 
-func bodyForm(m *descriptor.MethodDescriptorProto, info *httpInfo) []string {
+// bodyForm reports the code adding the fields of a form body for m to
+// bodyForms, the names of nested fields being joined by sep.
+func bodyForm(m *descriptor.MethodDescriptorProto, info *httpInfo, sep string) []string {
+	recv, fields := bodyFields(m, info)
+	return formParams("bodyForms", recv, sep, queryRepeat, fields)
+}
 
+// bodyFields reports the expression of the body of a request to meth in
+// the generated code, and the leaf fields of the body by their path.
+func bodyFields(m *descriptor.MethodDescriptorProto, info *httpInfo) (string, map[string]*descriptor.FieldDescriptorProto) {
 	queryParams := map[string]*descriptor.FieldDescriptorProto{}
+	recv := "in"
 	request := descInfo.Type[m.GetInputType()].(*descriptor.DescriptorProto)
 	if info.body != "*" {
 		bodyField := lookupField(m.GetInputType(), info.body)
 		request = descInfo.Type[bodyField.GetTypeName()].(*descriptor.DescriptorProto)
+		recv = "in" + fieldGetter(info.body)
 	}
 
-	// Possible query parameters are all leaf fields in the request or body.
+	// The body is made of all leaf fields in the request or body field,
+	// but the path parameters of a "*" body.
 	pathToLeaf := getLeafs(request, nil)
-	// Iterate in sorted order to
+	inPath := map[string]bool{}
+	if tmpl, err := parsePathTemplate(info.url); err == nil && info.body == "*" {
+		for _, v := range tmpl.variables {
+			inPath[v.fieldPath] = true
+		}
+	}
 	for path, leaf := range pathToLeaf {
-		// If, and only if, a leaf field is not a path parameter or a body parameter,
-		// it is a query parameter.
-		if lookupField(request.GetName(), leaf.GetName()) == nil {
+		if !inPath[path] {
 			queryParams[path] = leaf
 		}
 	}
+	return recv, queryParams
+}
+
+// filePart is a field of a multipart body sent as a file part: a bytes
+// field, or one with a (goapi.field).file option.
+type filePart struct {
+	path             string // field path, relative to the body
+	field            *descriptor.FieldDescriptorProto
+	filename         string // file name unless filenameField is set
+	contentType      string // content type unless contentTypeField is set
+	filenameField    string // path of the field holding the file name, if any
+	contentTypeField string // path of the field holding the content type, if any
+}
+
+// fileParts reports the file parts of the multipart body of meth, and
+// the other fields of the body, sent as form fields, by their path.
+func fileParts(m *descriptor.MethodDescriptorProto, info *httpInfo) ([]*filePart, map[string]*descriptor.FieldDescriptorProto, error) {
+	_, fields := bodyFields(m, info)
+	var paths []string
+	for path := range fields {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var parts []*filePart
+	for _, path := range paths {
+		field := fields[path]
+		file := getFieldOptions(field).GetFile()
+		if file == nil && field.GetType() != fieldTypeBytes {
+			continue
+		}
+		if field.GetType() != fieldTypeBytes && field.GetType() != fieldTypeString {
+			return nil, nil, fmt.Errorf("method %q: file part %q must be a string or bytes field", m.GetName(), path)
+		}
+		delete(fields, path)
+
+		// filename_field and content_type_field are siblings of the file.
+		parent := ""
+		if i := strings.LastIndexByte(path, '.'); i >= 0 {
+			parent = path[:i+1]
+		}
+		meta := func(option, name string) (string, error) {
+			if name == "" {
+				return "", nil
+			}
+			sibling, ok := fields[parent+name]
+			if !ok || sibling.GetType() != fieldTypeString || sibling.GetLabel() == fieldLabelRepeated {
+				return "", fmt.Errorf("method %q: %s %q of file part %q must be a string field next to it", m.GetName(), option, name, path)
+			}
+			delete(fields, parent+name)
+			return parent + name, nil
+		}
+		part := &filePart{
+			path:        path,
+			field:       field,
+			filename:    file.GetFilename(),
+			contentType: file.GetContentType(),
+		}
+		if part.filename == "" {
+			part.filename = field.GetName()
+		}
+		if part.contentType == "" {
+			part.contentType = "application/octet-stream"
+		}
+		var err error
+		if part.filenameField, err = meta("filename_field", file.GetFilenameField()); err != nil {
+			return nil, nil, err
+		}
+		if part.contentTypeField, err = meta("content_type_field", file.GetContentTypeField()); err != nil {
+			return nil, nil, err
+		}
+		parts = append(parts, part)
+	}
+	return parts, fields, nil
+}
+
+// multipartBody reports the code adding the form fields and the file parts
+// of a multipart/form-data body for meth, see fileParts. The nested names
+// of the form fields and of the file parts are joined by sep.
+func multipartBody(m *descriptor.MethodDescriptorProto, info *httpInfo, sep string) (string, error) {
+	recv, _ := bodyFields(m, info)
+	parts, fields, err := fileParts(m, info)
+	if err != nil {
+		return "", err
+	}
+	var files []string
+	for _, part := range parts {
+		content := "v"
+		if part.field.GetType() == fieldTypeString {
+			content = "[]byte(v)"
+		}
+
+		var b strings.Builder
+		if part.field.GetLabel() == fieldLabelRepeated {
+			fmt.Fprintf(&b, "for _, v := range %s%s {\n", recv, fieldGetter(part.path))
+		} else if part.field.GetType() == fieldTypeString {
+			fmt.Fprintf(&b, "if v := %s%s; v != \"\" {\n", recv, fieldGetter(part.path))
+		} else {
+			fmt.Fprintf(&b, "if v := %s%s; len(v) > 0 {\n", recv, fieldGetter(part.path))
+		}
+		fmt.Fprintf(&b, "\tpart := goapiFilePart{field: %q, filename: %q, contentType: %q, content: %s}\n", strings.ReplaceAll(part.path, ".", sep), part.filename, part.contentType, content)
+		if part.filenameField != "" {
+			fmt.Fprintf(&b, "\tif s := %s%s; s != \"\" {\n\t\tpart.filename = s\n\t}\n", recv, fieldGetter(part.filenameField))
+		}
+		if part.contentTypeField != "" {
+			fmt.Fprintf(&b, "\tif s := %s%s; s != \"\" {\n\t\tpart.contentType = s\n\t}\n", recv, fieldGetter(part.contentTypeField))
+		}
+		b.WriteString("\tfiles = append(files, part)\n}")
+		files = append(files, b.String())
+	}
+	return getMultipartContent(strings.Join(formParams("forms", recv, sep, queryRepeat, fields), "\n\t"), strings.Join(files, "\n\t"))
+}
+
+// getFieldOptions reports the (goapi.field) options of f, nil if none.
+func getFieldOptions(f *descriptor.FieldDescriptorProto) *goapipb.FieldOptions {
+	if f.GetOptions() == nil || !proto.HasExtension(f.GetOptions(), goapipb.E_Field) {
+		return nil
+	}
+	return proto.GetExtension(f.GetOptions(), goapipb.E_Field).(*goapipb.FieldOptions)
+}
 
-	return formParams("bodyForms", queryParams)
+func queryString(m *descriptor.MethodDescriptorProto, info *httpInfo, style string) []string {
+	queryParams := queryParams(m, info)
+	return formParams("params", "in", ".", style, queryParams)
 }
 
-func queryString(m *descriptor.MethodDescriptorProto) []string {
-	queryParams := queryParams(m)
-	return formParams("params", queryParams)
+// queryParamName reports the key of the query parameter of field, whose
+// path is name, with the query style.
+func queryParamName(name string, field *descriptor.FieldDescriptorProto, style string) string {
+	if style == queryBrackets && field.GetLabel() == fieldLabelRepeated {
+		return name + "[]"
+	}
+	return name
 }
 
-func formParams(keyName string, queryParams map[string]*descriptor.FieldDescriptorProto) []string {
+// formParams reports the code adding the fields of recv, by their path
+// whose names are joined by sep, to the url.Values keyName. Repeated
+// fields are encoded with the query style.
+func formParams(keyName, recv, sep, style string, queryParams map[string]*descriptor.FieldDescriptorProto) []string {
 	// We want to iterate over fields in a deterministic order
 	// to prevent spurious deltas when regenerating gapics.
 	fields := make([]string, 0, len(queryParams))
@@ -238,21 +529,25 @@ func formParams(keyName string, queryParams map[string]*descriptor.FieldDescript
 			field.GetType() != fieldTypeBytes &&
 			field.GetLabel() != fieldLabelRepeated
 		// key用命名的
-		key := path
-
-		var paramAdd string
-		// Handle well known protobuf types with special JSON encodings.
-		if strContains(wellKnownTypes, field.GetTypeName()) {
-			b := strings.Builder{}
-			b.WriteString(fmt.Sprintf("%s, err := json.Marshal(in%s)\n", field.GetJsonName(), accessor))
-			b.WriteString("if err != nil {\n")
-			b.WriteString("  return nil, err\n")
-			b.WriteString("}\n")
-			b.WriteString(fmt.Sprintf("%s[%q] = string(%s)", keyName, key, field.GetJsonName()))
-			paramAdd = b.String()
-		} else {
-			paramAdd = fmt.Sprintf("%s[%q] = fmt.Sprintf(%q, in%s)", keyName, key, "%v", accessor)
+		key := queryParamName(strings.ReplaceAll(path, ".", sep), field, style)
+		set := func(value string) string {
+			return fmt.Sprintf("%s.Add(%q, %s)", keyName, key, value)
 		}
+		// format reports the code to run first, if any, and the expression
+		// of the string form of the value v of field: well-known types are
+		// in JSON but unquoted, bytes base64 encoded like in JSON.
+		format := func(v string) (string, string) {
+			switch {
+			case strContains(wellKnownTypes, field.GetTypeName()):
+				return fmt.Sprintf("v, err := goapiQueryValue(%s)\nif err != nil {\n  return nil, err\n}\n", v), "v"
+			case field.GetType() == fieldTypeBytes:
+				return "", fmt.Sprintf("base64.StdEncoding.EncodeToString(%s)", v)
+			}
+			return "", fmt.Sprintf("fmt.Sprintf(%q, %s)", "%v", v)
+		}
+
+		setup, value := format(recv + accessor)
+		paramAdd := setup + set(value)
 
 		// Only required, singular, primitive field types should be added regardless.
 		if required && singularPrimitive {
@@ -263,11 +558,22 @@ func formParams(keyName string, queryParams map[string]*descriptor.FieldDescript
 
 		if field.GetLabel() == fieldLabelRepeated {
 			// It's a slice, so check for len > 0, nil slice returns 0.
-			params = append(params, fmt.Sprintf("if items := in%s; len(items) > 0 {", accessor))
+			params = append(params, fmt.Sprintf("if items := %s%s; len(items) > 0 {", recv, accessor))
 			b := strings.Builder{}
-			b.WriteString("for _, item := range items {\n")
-			b.WriteString(fmt.Sprintf("  %s[%q] = fmt.Sprintf(%q, item)\n", keyName, key, "%v"))
-			b.WriteString("}")
+			setup, value := format("item")
+			if style == queryComma {
+				b.WriteString("vs := make([]string, 0, len(items))\n")
+				b.WriteString("for _, item := range items {\n")
+				b.WriteString(setup)
+				b.WriteString(fmt.Sprintf("  vs = append(vs, %s)\n", value))
+				b.WriteString("}\n")
+				b.WriteString(set(`strings.Join(vs, ",")`))
+			} else {
+				b.WriteString("for _, item := range items {\n")
+				b.WriteString(setup)
+				b.WriteString(fmt.Sprintf("  %s\n", set(value)))
+				b.WriteString("}")
+			}
 			paramAdd = b.String()
 
 		} else if field.GetProto3Optional() {
@@ -276,19 +582,19 @@ func formParams(keyName string, queryParams map[string]*descriptor.FieldDescript
 			toks = toks[:len(toks)-1]
 			parentField := fieldGetter(strings.Join(toks, "."))
 			directLeafField := directAccess(path)
-			params = append(params, fmt.Sprintf("if in%s != nil && in%s != nil {", parentField, directLeafField))
+			params = append(params, fmt.Sprintf("if %s%s != nil && %s%s != nil {", recv, parentField, recv, directLeafField))
 		} else {
 			// Default values are type specific
 			switch field.GetType() {
 			// Degenerate case, field should never be a message because that implies it's not a leaf.
 			case fieldTypeMessage, fieldTypeBytes:
-				params = append(params, fmt.Sprintf("if in%s != nil {", accessor))
+				params = append(params, fmt.Sprintf("if %s%s != nil {", recv, accessor))
 			case fieldTypeString:
-				params = append(params, fmt.Sprintf(`if in%s != "" {`, accessor))
+				params = append(params, fmt.Sprintf(`if %s%s != "" {`, recv, accessor))
 			case fieldTypeBool:
-				params = append(params, fmt.Sprintf(`if in%s {`, accessor))
+				params = append(params, fmt.Sprintf(`if %s%s {`, recv, accessor))
 			default: // Handles all numeric types including enums
-				params = append(params, fmt.Sprintf(`if in%s != 0 {`, accessor))
+				params = append(params, fmt.Sprintf(`if %s%s != 0 {`, recv, accessor))
 			}
 		}
 		params = append(params, fmt.Sprintf("\t%s", paramAdd))
@@ -298,9 +604,8 @@ func formParams(keyName string, queryParams map[string]*descriptor.FieldDescript
 	return params
 }
 
-func queryParams(m *descriptor.MethodDescriptorProto) map[string]*descriptor.FieldDescriptorProto {
+func queryParams(m *descriptor.MethodDescriptorProto, info *httpInfo) map[string]*descriptor.FieldDescriptorProto {
 	queryParams := map[string]*descriptor.FieldDescriptorProto{}
-	info := getHTTPInfo(m)
 	if info == nil {
 		return queryParams
 	}
@@ -309,7 +614,7 @@ func queryParams(m *descriptor.MethodDescriptorProto) map[string]*descriptor.Fie
 		return queryParams
 	}
 
-	pathParams := pathParams(m)
+	pathParams := pathParams(m, info)
 	// Minor hack: we want to make sure that the body parameter is NOT a query parameter.
 	pathParams[info.body] = &descriptor.FieldDescriptorProto{}
 
@@ -412,20 +717,19 @@ func getLeafs(msg *descriptor.DescriptorProto, excludedFields ...*descriptor.Fie
 	return pathsToLeafs
 }
 
-func pathParams(m *descriptor.MethodDescriptorProto) map[string]*descriptor.FieldDescriptorProto {
+func pathParams(m *descriptor.MethodDescriptorProto, info *httpInfo) map[string]*descriptor.FieldDescriptorProto {
 	pathParams := map[string]*descriptor.FieldDescriptorProto{}
-	info := getHTTPInfo(m)
 	if info == nil {
 		return pathParams
 	}
 
-	// Match using the curly braces but don't include them in the grouping.
-	re := regexp.MustCompile("{([^}]+)}")
-	for _, p := range re.FindAllStringSubmatch(info.url, -1) {
-		// In the returned slice, the zeroth element is the full regex match,
-		// and the subsequent elements are the sub group matches.
-		// See the docs for FindStringSubmatch for further details.
-		param := strings.Split(p[1], "=")[0]
+	// Malformed templates are reported by genRestMethodCode.
+	tmpl, err := parsePathTemplate(info.url)
+	if err != nil {
+		return pathParams
+	}
+	for _, v := range tmpl.variables {
+		param := v.fieldPath
 		field := lookupField(m.GetInputType(), param)
 		if field == nil {
 			continue
@@ -533,6 +837,11 @@ func upperFirst(s string) string {
 
 // isRequired returns if a field is annotated as REQUIRED or not.
 func isRequired(field *descriptor.FieldDescriptorProto) bool {
+	return hasFieldBehavior(field, annotations.FieldBehavior_REQUIRED)
+}
+
+// hasFieldBehavior reports whether field is annotated with the behavior b.
+func hasFieldBehavior(field *descriptor.FieldDescriptorProto, b annotations.FieldBehavior) bool {
 	if field.GetOptions() == nil {
 		return false
 	}
@@ -540,8 +849,8 @@ func isRequired(field *descriptor.FieldDescriptorProto) bool {
 	eBehav := proto.GetExtension(field.GetOptions(), annotations.E_FieldBehavior)
 
 	behaviors := eBehav.([]annotations.FieldBehavior)
-	for _, b := range behaviors {
-		if b == annotations.FieldBehavior_REQUIRED {
+	for _, fb := range behaviors {
+		if fb == b {
 			return true
 		}
 	}
@@ -0,0 +1,199 @@
+package goapi
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/open-api-go/protoc-gen-go_api/goapi/annotations"
+	_ "google.golang.org/genproto/googleapis/api/annotations"
+	gengo "google.golang.org/protobuf/cmd/protoc-gen-go/internal_gengo"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	_ "google.golang.org/protobuf/types/known/durationpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// TestRoundTrip generates the client and the server of
+// testdata/roundtrip/roundtrip.textproto, and runs the tests in
+// testdata/roundtrip against them: the server must decode what the client
+// sends. The generated code needs github.com/open-api-go/grequests, taken
+// from the directory $GOAPI_GREQUESTS if set, else from the module proxy,
+// and the routers of the server backends it uses.
+func TestRoundTrip(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the go command")
+	}
+	goCmd, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	root, err := filepath.Abs("..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		param string
+		tests []string // files of testdata/roundtrip
+	}{
+		{"module=example.com/roundtrip,server=http", []string{"roundtrip_test.go"}},
+		{"module=example.com/roundtrip,server=http,form_separator=_,query_style=comma", []string{"roundtrip_test.go"}},
+		{"module=example.com/roundtrip,server=http,server=gin,server=echo,server=chi",
+			[]string{"roundtrip_test.go", "gin_test.go", "echo_test.go", "chi_test.go"}},
+	} {
+		t.Run(tt.param, func(t *testing.T) {
+			testRoundTrip(t, goCmd, root, tt.param, tt.tests)
+		})
+	}
+}
+
+// testRoundTrip runs the tests in the files tests of testdata/roundtrip in
+// a module generated with param, using the go command goCmd and the module
+// in root.
+func testRoundTrip(t *testing.T, goCmd, root, param string, tests []string) {
+	dir := t.TempDir()
+	run := func(args ...string) ([]byte, error) {
+		cmd := exec.Command(goCmd, args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GONOSUMDB=github.com/open-api-go")
+		return cmd.CombinedOutput()
+	}
+
+	files := generateRoundTrip(t, param)
+	for name, content := range files {
+		writeFile(t, filepath.Join(dir, name), content)
+	}
+	for _, name := range tests {
+		bs, err := ioutil.ReadFile(filepath.Join("testdata", "roundtrip", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, filepath.Join(dir, name), string(bs))
+	}
+	mod, err := ioutil.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := ioutil.ReadFile(filepath.Join(root, "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gomod := strings.Replace(string(mod), "module github.com/open-api-go/protoc-gen-go_api",
+		"module example.com/roundtrip", 1)
+	gomod += "\nrequire github.com/open-api-go/protoc-gen-go_api v0.0.0\n" +
+		"replace github.com/open-api-go/protoc-gen-go_api => " + root + "\n"
+	if dir := os.Getenv("GOAPI_GREQUESTS"); dir != "" {
+		gomod += "require github.com/open-api-go/grequests v0.0.0\n" +
+			"replace github.com/open-api-go/grequests => " + dir + "\n"
+	}
+	writeFile(t, filepath.Join(dir, "go.mod"), gomod)
+	writeFile(t, filepath.Join(dir, "go.sum"), string(sum))
+	if out, err := run("get", "github.com/open-api-go/grequests"); err != nil {
+		t.Skipf("github.com/open-api-go/grequests is not available, set GOAPI_GREQUESTS:\n%s", out)
+	}
+	for _, r := range []struct{ server, path string }{
+		{"gin", "github.com/gin-gonic/gin@v1.10.0"},
+		{"echo", "github.com/labstack/echo/v4@v4.9.1"},
+		{"chi", "github.com/go-chi/chi/v5@v5.3.1"},
+	} {
+		if !strings.Contains(param, "server="+r.server) {
+			continue
+		}
+		if out, err := run("get", r.path); err != nil {
+			t.Skipf("%s is not available:\n%s", r.path, out)
+		}
+	}
+
+	if out, err := run("vet", "."); err != nil {
+		t.Fatalf("go vet: %v\n%s", err, out)
+	}
+	if out, err := run("test", "."); err != nil {
+		t.Fatalf("go test: %v\n%s", err, out)
+	}
+}
+
+// generateRoundTrip runs Gen and protoc-gen-go with param on
+// testdata/roundtrip/roundtrip.textproto, and reports the files they
+// generate by name.
+func generateRoundTrip(t *testing.T, param string) map[string]string {
+	bs, err := ioutil.ReadFile(filepath.Join("testdata", "roundtrip", "roundtrip.textproto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := new(descriptorpb.FileDescriptorProto)
+	if err := prototext.Unmarshal(bs, fd); err != nil {
+		t.Fatal(err)
+	}
+	req := &pluginpb.CodeGeneratorRequest{FileToGenerate: []string{fd.GetName()}}
+	seen := map[string]bool{}
+	var add func(path string)
+	add = func(path string) {
+		if seen[path] {
+			return
+		}
+		seen[path] = true
+		dep, err := protoregistry.GlobalFiles.FindFileByPath(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		imports := dep.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			add(imports.Get(i).Path())
+		}
+		req.ProtoFile = append(req.ProtoFile, protodesc.ToFileDescriptorProto(dep))
+	}
+	for _, dep := range fd.GetDependency() {
+		add(dep)
+	}
+	req.ProtoFile = append(req.ProtoFile, fd)
+
+	// Marshal the request as protoc sends it.
+	if bs, err = proto.Marshal(req); err != nil {
+		t.Fatal(err)
+	}
+	req = new(pluginpb.CodeGeneratorRequest)
+	if err := proto.Unmarshal(bs, req); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	req.Parameter = proto.String(param)
+	resp, err := Gen(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range resp.GetFile() {
+		files[f.GetName()] = f.GetContent()
+	}
+
+	req.Parameter = proto.String("module=example.com/roundtrip")
+	gen, err := protogen.Options{}.New(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range gen.Files {
+		if f.Generate {
+			gengo.GenerateFile(gen, f)
+		}
+	}
+	for _, f := range gen.Response().GetFile() {
+		files[f.GetName()] = f.GetContent()
+	}
+	return files
+}
+
+func writeFile(t *testing.T, name, content string) {
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
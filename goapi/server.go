@@ -0,0 +1,163 @@
+package goapi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/open-api-go/protoc-gen-go_api/pbinfo"
+)
+
+// serverBackend is a router the generated server can register its routes
+// on. The routes, the binding and the errors are shared by all backends
+// in the runtime file, a backend only adds the function registering the
+// routes on its router.
+type serverBackend struct {
+	Name    string            // value of the server parameter
+	Import  pbinfo.ImportSpec // package of the router, none for net/http
+	Func    string            // registration function, Register<Service><Func>
+	Router  string            // type of the router parameter
+	Helper  string            // runtime function registering []*goapiRoute on a Router
+	Runtime string            // runtime code declaring Helper
+}
+
+// serverBackends are the backends the server parameter selects from.
+var serverBackends = []*serverBackend{
+	{
+		Name:   serverHTTP,
+		Func:   "HTTPServer",
+		Router: "*http.ServeMux",
+		Helper: "goapiRegister",
+	},
+	{
+		Name:    "gin",
+		Import:  pbinfo.ImportSpec{Name: "gin", Path: "github.com/gin-gonic/gin"},
+		Func:    "GinRoutes",
+		Router:  "gin.IRoutes",
+		Helper:  "goapiRegisterGin",
+		Runtime: ginRuntime,
+	},
+	{
+		Name:    "echo",
+		Import:  pbinfo.ImportSpec{Name: "echo", Path: "github.com/labstack/echo/v4"},
+		Func:    "EchoRoutes",
+		Router:  "goapiEchoRouter",
+		Helper:  "goapiRegisterEcho",
+		Runtime: echoRuntime,
+	},
+	{
+		Name:    "chi",
+		Import:  pbinfo.ImportSpec{Name: "chi", Path: "github.com/go-chi/chi/v5"},
+		Func:    "ChiRoutes",
+		Router:  "chi.Router",
+		Helper:  "goapiRegisterChi",
+		Runtime: chiRuntime,
+	},
+}
+
+// isBackendImport reports whether path is the package of a backend.
+func isBackendImport(path string) bool {
+	for _, b := range serverBackends {
+		if b.Import.Path != "" && b.Import.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupServerBackend reports the backend named name, nil if none.
+func lookupServerBackend(name string) *serverBackend {
+	for _, b := range serverBackends {
+		if b.Name == name {
+			return b
+		}
+	}
+	return nil
+}
+
+// parseServerMethod adds meth, served on the HTTP rule info and its
+// additional bindings, to the server side of data. Streaming methods
+// can't be served over plain HTTP and are left out.
+func parseServerMethod(data *ServiceData, meth *descriptor.MethodDescriptorProto, info *httpInfo, imports *importSet, opts *Options) error {
+	if meth.GetClientStreaming() || meth.GetServerStreaming() {
+		return nil
+	}
+	reqTyp, err := imports.typeName(meth.GetInputType())
+	if err != nil {
+		return err
+	}
+	resTyp, err := imports.typeName(meth.GetOutputType())
+	if err != nil {
+		return err
+	}
+	mth := &MethodData{
+		ServName:   data.ServName,
+		MethName:   meth.GetName(),
+		Comment:    getComment(meth),
+		Doc:        goDoc(meth.GetName(), getComment(meth), getDetachedComments(meth)),
+		ReqTyp:     reqTyp,
+		ResTyp:     resTyp,
+		Deprecated: meth.GetOptions().GetDeprecated(),
+	}
+	if mth.Deprecated {
+		mth.Doc = deprecatedDoc(mth.Doc)
+	}
+	data.ServerMethods = append(data.ServerMethods, mth)
+
+	for _, rule := range append([]*httpInfo{info}, info.bindings...) {
+		route, err := parseRoute(meth, rule, opts)
+		if err != nil {
+			return err
+		}
+		route.ReqTyp = reqTyp
+		data.Routes = append(data.Routes, route)
+	}
+	return nil
+}
+
+// parseRoute parses the route meth is served on for the HTTP rule info.
+// The rule was already checked while generating the client.
+func parseRoute(meth *descriptor.MethodDescriptorProto, info *httpInfo, opts *Options) (*RouteData, error) {
+	tmpl, err := parsePathTemplate(info.url)
+	if err != nil {
+		return nil, fmt.Errorf("method %q: %v", meth.GetName(), err)
+	}
+	route := &RouteData{
+		MethName:     meth.GetName(),
+		Verb:         strings.ToUpper(info.verb),
+		Path:         info.url,
+		Prefix:       tmpl.prefix(),
+		Pattern:      tmpl.pattern(),
+		VerbSuffix:   tmpl.verb != "",
+		Segments:     tmpl.routerSegments(),
+		Body:         info.body,
+		ResponseBody: info.responseBody,
+		HTTPBody:     meth.GetOutputType() == httpBodyType,
+	}
+	for _, v := range tmpl.variables {
+		route.Vars = append(route.Vars, v.fieldPath)
+	}
+	if info.body != "" {
+		route.BodyFormat = info.format
+		if route.BodyFormat == "" {
+			route.BodyFormat = opts.DefaultBody
+		}
+		if info.body != "*" && lookupField(meth.GetInputType(), info.body) == nil {
+			return nil, fmt.Errorf("method %q: body %q is not a field of %s", meth.GetName(), info.body, meth.GetInputType())
+		}
+		if route.BodyFormat == bodyMULTI {
+			parts, _, err := fileParts(meth, info)
+			if err != nil {
+				return nil, err
+			}
+			for _, part := range parts {
+				route.Files = append(route.Files, &RouteFile{
+					Name:             strings.ReplaceAll(part.path, ".", opts.FormSeparator),
+					FilenameField:    part.filenameField,
+					ContentTypeField: part.contentTypeField,
+				})
+			}
+		}
+	}
+	return route, nil
+}
@@ -0,0 +1,13 @@
+package roundtrip
+
+import (
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestChi(t *testing.T) {
+	r := chi.NewRouter()
+	RegisterEchoChiRoutes(r, echoServer{})
+	testRead(t, serve(t, r))
+}
@@ -0,0 +1,13 @@
+package roundtrip
+
+import (
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestEcho(t *testing.T) {
+	e := echo.New()
+	RegisterEchoEchoRoutes(e, echoServer{})
+	testRead(t, serve(t, e))
+}
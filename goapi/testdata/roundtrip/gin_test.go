@@ -0,0 +1,14 @@
+package roundtrip
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGin(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	RegisterEchoGinRoutes(r, echoServer{})
+	testRead(t, serve(t, r))
+}
@@ -0,0 +1,130 @@
+package roundtrip
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type echoServer struct{}
+
+func (echoServer) Upload(ctx context.Context, in *UploadRequest) (*UploadRequest, error) {
+	return in, nil
+}
+
+func (echoServer) Submit(ctx context.Context, in *FormRequest) (*FormRequest, error) {
+	return in, nil
+}
+
+func (echoServer) Search(ctx context.Context, in *FormRequest) (*FormRequest, error) {
+	return in, nil
+}
+
+func (echoServer) Read(ctx context.Context, in *ReadRequest) (*ReadRequest, error) {
+	return in, nil
+}
+
+func newClient(t *testing.T) EchoService {
+	mux := http.NewServeMux()
+	RegisterEchoHTTPServer(mux, echoServer{})
+	return serve(t, mux)
+}
+
+// serve serves h for the duration of t, and reports a client of it.
+func serve(t *testing.T, h http.Handler) EchoService {
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return NewEchoClient(WithBaseURL(srv.URL))
+}
+
+func TestUpload(t *testing.T) {
+	c := newClient(t)
+	for _, in := range []*UploadRequest{
+		{Id: "1"},
+		{
+			Id:          "2",
+			Content:     []byte("\x89PNG"),
+			Filename:    "a.png",
+			ContentType: "image/png",
+			Notes:       "some notes",
+			Attachments: [][]byte{[]byte("a"), []byte("b")},
+			Title:       "a title",
+			Tags:        []string{"x", "y"},
+			Meta:        &Meta{Owner: "me", Ids: []int32{1, 2}, Sig: []byte{0, 1, 2}},
+		},
+	} {
+		out, err := c.Upload(context.Background(), in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !proto.Equal(out, in) {
+			t.Errorf("Upload(%v) = %v", in, out)
+		}
+	}
+}
+
+func TestForm(t *testing.T) {
+	c := newClient(t)
+	for _, in := range []*FormRequest{
+		{Id: "1"},
+		{
+			Id:          "2",
+			Title:       "a title, with a comma",
+			Tags:        []string{"x", "y"},
+			Meta:        &Meta{Owner: "me", Ids: []int32{1, 2}, Sig: []byte{0xff, 0xfe}},
+			Data:        []byte("\x00binary\xff"),
+			Chunks:      [][]byte{[]byte("a"), []byte("b")},
+			ContentType: "text/plain",
+			Since:       timestamppb.New(time.Date(2017, 1, 15, 1, 30, 15, 10, time.UTC)),
+			Windows:     []*durationpb.Duration{durationpb.New(1500 * time.Millisecond), durationpb.New(time.Hour)},
+		},
+	} {
+		out, err := c.Submit(context.Background(), in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !proto.Equal(out, in) {
+			t.Errorf("Submit(%v) = %v", in, out)
+		}
+		out, err = c.Search(context.Background(), in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !proto.Equal(out, in) {
+			t.Errorf("Search(%v) = %v", in, out)
+		}
+	}
+}
+
+// testRead checks that the catch-all route of Read and the route of Search,
+// which share the prefix /v1/, both get their requests.
+func testRead(t *testing.T, c EchoService) {
+	for _, path := range []string{"a", "a/b/c", "items/1"} {
+		in := &ReadRequest{Path: path}
+		out, err := c.Read(context.Background(), in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !proto.Equal(out, in) {
+			t.Errorf("Read(%v) = %v", in, out)
+		}
+	}
+	in := &FormRequest{Id: "1", Title: "a title"}
+	out, err := c.Search(context.Background(), in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(out, in) {
+		t.Errorf("Search(%v) = %v", in, out)
+	}
+}
+
+func TestRead(t *testing.T) {
+	testRead(t, newClient(t))
+}
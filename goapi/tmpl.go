@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"html/template"
 	"log"
+	texttemplate "text/template"
 )
 
 var goapiTmpl = `// Code generated by protoc-gen-go_api(github.com/open-api-go/protoc-gen-go_api version={{ .Version }}). DO NOT EDIT.
@@ -14,82 +15,1440 @@ package {{ .GoPackage }}
 import (
 	context "context"
 	fmt "fmt"
+	json "encoding/json"
 	strings "strings"
 	grequests "github.com/open-api-go/grequests"
+	protojson "google.golang.org/protobuf/encoding/protojson"
+	time "time"
+	url "net/url"
+	base64 "encoding/base64"
+{{- range .Imports }}
+	{{ .Name }} "{{ .Path | html }}"
+{{- end }}
 )
 
 // Reference imports to suppress errors if they are not otherwise used.
 var	_ = context.Background
 var	_ = fmt.Println
+var	_ = json.Marshal
 var	_ = strings.Trim
 var	_ = grequests.Get
+var	_ = protojson.Unmarshal
+var	_ = time.Second
+var	_ = url.Values{}
+var	_ = base64.StdEncoding
 
 {{ range .Services }}
 // Client API for {{ .ServName }} service
-
+{{ if .Doc }}
+{{ .Doc | html }}
+{{- end }}
 type {{ .ServName }}Service interface {
 {{- range .Methods }}
-	// {{ .MethName }} {{ .Comment }}
-	{{ .MethName }}(ctx context.Context, in *{{ .ReqTyp }}, opts ...grequests.RequestOption) (*grequests.Response, error)
+	{{ .Doc | html }}
+	{{ .MethName }}(ctx context.Context, in *{{ .ReqTyp }}, opts ...grequests.RequestOption) (*{{ .ResTyp }}, error)
+{{- if $.RawResponse }}
+	// {{ .RawName }} is like {{ .MethName }} but returns the raw response.
+{{- if .Deprecated }}
+	//
+	// Deprecated: Do not use.
+{{- end }}
+	{{ .RawName }}(ctx context.Context, in *{{ .ReqTyp }}, opts ...grequests.RequestOption) (*grequests.Response, error)
+{{- end }}
 {{- end }}
 }
 
 type {{ unexport .ServName }}Service struct {
-	addr    string            // start with http/https
+	addr    string             // start with http/https
 	session *grequests.Session // requests session
+	config  *goapiClientConfig // options the client was made with
 }
 
-func New{{ .ServName }}Service(opts ...grequests.RequestOption) {{ .ServName }}Service {
+// New{{ .ServName }}Client returns a client of {{ .ServName }} service configured by opts.
+// It sends the requests to {{ .BaseURL | html }} unless WithBaseURL or WithEndpoint is given.
+{{- if .Deprecated }}
+//
+// Deprecated: Do not use.
+{{- end }}
+func New{{ .ServName }}Client(opts ...ClientOption) {{ .ServName }}Service {
+	cfg := &goapiClientConfig{baseURL: {{ printf "%q" .BaseURL | html }}}
+	for _, o := range opts {
+		o(cfg)
+	}
 	return &{{ unexport .ServName }}Service{
-		addr:   "https://{{ .PkgName }}",
-		session: grequests.NewSession(opts...),
+		addr:    cfg.baseURL,
+		session: grequests.NewSession(cfg.requestOpts...),
+		config:  cfg,
 	}
 }
 
+// New{{ .ServName }}Service returns a client of {{ .ServName }} service applying opts to every request.
+{{- if .Deprecated }}
+//
+// Deprecated: Do not use.
+{{- end }}
+func New{{ .ServName }}Service(opts ...grequests.RequestOption) {{ .ServName }}Service {
+	return New{{ .ServName }}Client(WithRequestOptions(opts...))
+}
+
 {{ range .Methods }}
-func (c *{{ unexport .ServName }}Service) {{ .MethName }}(ctx context.Context, in *{{ .ReqTyp }}, opts ...grequests.RequestOption) (*grequests.Response, error) {
+func (c *{{ unexport .ServName }}Service) {{ .MethName }}(ctx context.Context, in *{{ .ReqTyp }}, opts ...grequests.RequestOption) (*{{ .ResTyp }}, error) {
+	resp, err := c.{{ .RawName }}(ctx, in, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := goapiCheckResponse(resp); err != nil {
+		return nil, err
+	}
+	{{ .ResCode | html }}
+}
+
+func (c *{{ unexport .ServName }}Service) {{ .RawName }}(ctx context.Context, in *{{ .ReqTyp }}, opts ...grequests.RequestOption) (*grequests.Response, error) {
+{{- if .Timeout }}
+	return goapiWithTimeout(ctx, {{ .Timeout }}, func(ctx context.Context) (*grequests.Response, error) {
+		{{ .ReqCode | html }}
+	})
+{{- else }}
 	{{ .ReqCode | html }}
+{{- end }}
 }
 {{ end -}}
 {{ end -}}
 `
 
+var serverTmpl = `// Code generated by protoc-gen-go_api(github.com/open-api-go/protoc-gen-go_api version={{ .Version }}). DO NOT EDIT.
+// source: {{ .Source }}
+
+package {{ .GoPackage }}
+
+import (
+	context "context"
+	http "net/http"
+	regexp "regexp"
+{{- range .ServerImports }}
+	{{ .Name }} "{{ .Path }}"
+{{- end }}
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = context.Background
+var _ = regexp.MustCompile
+{{ range .Services }}
+// {{ .ServName }}HTTPServer is the server API for {{ .ServName }} service.
+// It isn't named {{ .ServName }}Server, which protoc-gen-go-grpc generates
+// in the same package.
+type {{ .ServName }}HTTPServer interface {
+{{- range .ServerMethods }}
+	{{ .Doc }}
+	{{ .MethName }}(context.Context, *{{ .ReqTyp }}) (*{{ .ResTyp }}, error)
+{{- end }}
+}
+
+{{- $serv := .ServName }}
+{{- range $.Backends }}
+
+// Register{{ $serv }}{{ .Func }} registers the routes of {{ $serv }} service on r.
+// Errors returned by srv are written as is if they are an *APIError, as
+// a 500 otherwise.
+func Register{{ $serv }}{{ .Func }}(r {{ .Router }}, srv {{ $serv }}HTTPServer) {
+	{{ .Helper }}(r, {{ unexport $serv }}HTTPRoutes(srv))
+}
+{{- end }}
+
+// {{ unexport .ServName }}HTTPRoutes reports the routes of {{ .ServName }} service served by srv.
+func {{ unexport .ServName }}HTTPRoutes(srv {{ .ServName }}HTTPServer) []*goapiRoute {
+	return []*goapiRoute{
+{{- range .Routes }}
+		{
+			method:     {{ printf "%q" .Verb }},
+			path:       {{ printf "%q" .Path }},
+			prefix:     {{ printf "%q" .Prefix }},
+			pattern:    regexp.MustCompile({{ printf "%q" .Pattern }}),
+			vars:       []string{ {{- range $i, $v := .Vars }}{{ if $i }}, {{ end }}{{ printf "%q" $v }}{{ end -}} },
+			verbSuffix: {{ .VerbSuffix }},
+			segments:   []string{ {{- range $i, $v := .Segments }}{{ if $i }}, {{ end }}{{ printf "%q" $v }}{{ end -}} },
+			handle: func(w http.ResponseWriter, r *http.Request, vars map[string]string) {
+				in := new({{ .ReqTyp }})
+{{- if .Files }}
+				files := map[string]goapiFileField{
+{{- range .Files }}
+					{{ printf "%q" .Name }}: {filenameField: {{ printf "%q" .FilenameField }}, contentTypeField: {{ printf "%q" .ContentTypeField }}},
+{{- end }}
+				}
+				if err := goapiBind(r, in, {{ printf "%q" .Body }}, {{ printf "%q" .BodyFormat }}, vars, files); err != nil {
+{{- else }}
+				if err := goapiBind(r, in, {{ printf "%q" .Body }}, {{ printf "%q" .BodyFormat }}, vars, nil); err != nil {
+{{- end }}
+					goapiWriteError(w, err)
+					return
+				}
+				out, err := srv.{{ .MethName }}(r.Context(), in)
+				if err != nil {
+					goapiWriteError(w, err)
+					return
+				}
+{{- if .HTTPBody }}
+				w.Header().Set("Content-Type", out.GetContentType())
+				w.Write(out.GetData())
+{{- else }}
+				goapiWriteResponse(w, out, {{ printf "%q" .ResponseBody }})
+{{- end }}
+			},
+		},
+{{- end }}
+	}
+}
+{{ end -}}
+`
+
+// Runtime code of the server backends, see serverBackends. net/http needs
+// none, its goapiRegister is used by the fakes too.
+var (
+	ginRuntime = `
+// goapiRegisterGin registers routes on r. Every segment but the literal
+// ones is a positional parameter, :p<i> or *p<i>, and the dispatcher does
+// the actual matching. gin doesn't allow a catch-all next to other routes,
+// so the routes under the prefix of a catch-all, e.g. /v1/items/:p2 under
+// /v1/*p1, are registered on the catch-all of the same method instead.
+func goapiRegisterGin(r gin.IRoutes, routes []*goapiRoute) {
+	paths := make([]string, len(routes))
+	for i, rt := range routes {
+		paths[i] = goapiRouterPath(rt, func(i int, catchAll bool) string {
+			if catchAll {
+				return fmt.Sprintf("*p%d", i)
+			}
+			return fmt.Sprintf(":p%d", i)
+		})
+	}
+	ds := goapiDispatchers{}
+	for i, rt := range routes {
+		// Take the path of the catch-all with the shortest prefix covering
+		// the route, if any.
+		p, covered := paths[i], ""
+		for j, ca := range routes {
+			if ca.method != rt.method || !ca.catchAll() {
+				continue
+			}
+			prefix := paths[j][:strings.LastIndexByte(paths[j], '/')+1]
+			if strings.HasPrefix(paths[i], prefix) && (covered == "" || len(prefix) < len(covered)) {
+				p, covered = paths[j], prefix
+			}
+		}
+		ds.get(rt.method+" "+p, func(d *goapiDispatcher) {
+			r.Handle(rt.method, p, func(c *gin.Context) {
+				d.serveGroup(c.Writer, c.Request, c.FullPath(), p)
+			})
+		}).add(rt)
+	}
+}`
+
+	echoRuntime = `
+// goapiEchoRouter is implemented by *echo.Echo and *echo.Group.
+type goapiEchoRouter interface {
+	Add(method, path string, handler echo.HandlerFunc, middleware ...echo.MiddlewareFunc) *echo.Route
+}
+
+// goapiRegisterEcho registers routes on r, with the parameters named
+// like goapiRegisterGin does, :p<i> and * for the rest of the path.
+func goapiRegisterEcho(r goapiEchoRouter, routes []*goapiRoute) {
+	ds := goapiDispatchers{}
+	for _, rt := range routes {
+		p := goapiRouterPath(rt, func(i int, catchAll bool) string {
+			if catchAll {
+				return "*"
+			}
+			return fmt.Sprintf(":p%d", i)
+		})
+		ds.get(rt.method+" "+p, func(d *goapiDispatcher) {
+			r.Add(rt.method, p, func(c echo.Context) error {
+				d.serveGroup(c.Response(), c.Request(), c.Path(), p)
+				return nil
+			})
+		}).add(rt)
+	}
+}`
+
+	chiRuntime = `
+// goapiRegisterChi registers routes on r, with the parameters named
+// like goapiRegisterGin does, {p<i>} and * for the rest of the path.
+func goapiRegisterChi(r chi.Router, routes []*goapiRoute) {
+	ds := goapiDispatchers{}
+	for _, rt := range routes {
+		p := goapiRouterPath(rt, func(i int, catchAll bool) string {
+			if catchAll {
+				return "*"
+			}
+			return fmt.Sprintf("{p%d}", i)
+		})
+		ds.get(rt.method+" "+p, func(d *goapiDispatcher) {
+			r.Method(rt.method, p, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				d.serveGroup(w, req, chi.RouteContext(req.Context()).RoutePattern(), p)
+			}))
+		}).add(rt)
+	}
+}`
+)
+
+var mockTmpl = `// Code generated by protoc-gen-go_api(github.com/open-api-go/protoc-gen-go_api version={{ .Version }}). DO NOT EDIT.
+// source: {{ .Source }}
+
+// The file imports testing, so it's only built with the tag goapitest.
+
+//go:build goapitest
+// +build goapitest
+
+package {{ .GoPackage }}
+
+import (
+	context "context"
+	sync "sync"
+	testing "testing"
+	grequests "github.com/open-api-go/grequests"
+{{- range .Imports }}
+	{{ .Name }} "{{ .Path }}"
+{{- end }}
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = context.Background
+var _ = grequests.Get
+var _ testing.TB
+{{ range .Services }}
+{{- $serv := .ServName }}
+// Mock{{ $serv }}Service is a mock {{ $serv }}Service for tests. A method returns the
+// values set with <Method>Returns, zero values by default, or calls
+// <Method>Func if set. The requests are recorded, see <Method>Calls.
+type Mock{{ $serv }}Service struct {
+{{- range .MockMethods }}
+	// {{ .MethName }}Func, if set, is called by {{ .MethName }} instead of returning the values set with {{ .MethName }}Returns.
+	{{ .MethName }}Func func(ctx context.Context, in *{{ .ReqTyp }}, opts ...grequests.RequestOption) (*{{ .ResTyp }}, error)
+{{- end }}
+
+	mu sync.Mutex
+{{- range .MockMethods }}
+	{{ unexport .MethName }}Calls []*{{ .ReqTyp }}
+	{{ unexport .MethName }}Out   *{{ .ResTyp }}
+	{{ unexport .MethName }}Err   error
+{{- end }}
+}
+
+var _ {{ $serv }}Service = (*Mock{{ $serv }}Service)(nil)
+{{ range .MockMethods }}
+func (m *Mock{{ $serv }}Service) {{ .MethName }}(ctx context.Context, in *{{ .ReqTyp }}, opts ...grequests.RequestOption) (*{{ .ResTyp }}, error) {
+	m.mu.Lock()
+	m.{{ unexport .MethName }}Calls = append(m.{{ unexport .MethName }}Calls, in)
+	fn, out, err := m.{{ .MethName }}Func, m.{{ unexport .MethName }}Out, m.{{ unexport .MethName }}Err
+	m.mu.Unlock()
+	if fn != nil {
+		return fn(ctx, in, opts...)
+	}
+	return out, err
+}
+
+// {{ .MethName }}Returns sets the values {{ .MethName }} returns.
+func (m *Mock{{ $serv }}Service) {{ .MethName }}Returns(out *{{ .ResTyp }}, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.{{ unexport .MethName }}Out, m.{{ unexport .MethName }}Err = out, err
+}
+
+// {{ .MethName }}Calls reports the requests {{ .MethName }} was called with, in order.
+func (m *Mock{{ $serv }}Service) {{ .MethName }}Calls() []*{{ .ReqTyp }} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*{{ .ReqTyp }}(nil), m.{{ unexport .MethName }}Calls...)
+}
+
+// Assert{{ .MethName }}Called fails t unless {{ .MethName }} was called n times.
+func (m *Mock{{ $serv }}Service) Assert{{ .MethName }}Called(t testing.TB, n int) {
+	t.Helper()
+	if got := len(m.{{ .MethName }}Calls()); got != n {
+		t.Errorf("Mock{{ $serv }}Service.{{ .MethName }} called %d times, want %d", got, n)
+	}
+}
+{{ end }}
+{{- end -}}
+`
+
+var fakeTmpl = `// Code generated by protoc-gen-go_api(github.com/open-api-go/protoc-gen-go_api version={{ .Version }}). DO NOT EDIT.
+// source: {{ .Source }}
+
+// The file imports net/http/httptest, so it's only built with the tag goapitest.
+
+//go:build goapitest
+// +build goapitest
+
+package {{ .GoPackage }}
+
+import (
+	context "context"
+	http "net/http"
+	httptest "net/http/httptest"
+	grequests "github.com/open-api-go/grequests"
+{{- range .FakeImports }}
+	{{ .Name }} "{{ .Path }}"
+{{- end }}
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = context.Background
+{{ range .Services }}
+{{- $serv := .ServName }}
+// Fake{{ $serv }} is a fake {{ $serv }} service for tests. It serves the routes of
+// {{ $serv }} service on an httptest.Server, calls the <Method>Func handlers
+// and records the requests received. Methods without a handler fail with
+// UNIMPLEMENTED. Set the handlers before calling the fake.
+type Fake{{ $serv }} struct {
+	Server *httptest.Server
+{{ range .ServerMethods }}
+	// {{ .MethName }}Func handles {{ .MethName }}, additional bindings included.
+	{{ .MethName }}Func func(ctx context.Context, in *{{ .ReqTyp }}) (*{{ .ResTyp }}, error)
+{{- end }}
+
+	calls goapiFakeCalls
+}
+
+// NewFake{{ $serv }} starts a Fake{{ $serv }}, stop it with Close.
+func NewFake{{ $serv }}() *Fake{{ $serv }} {
+	f := new(Fake{{ $serv }})
+	mux := http.NewServeMux()
+	goapiRegister(mux, {{ unexport $serv }}HTTPRoutes(fake{{ $serv }}Server{f}))
+	f.Server = httptest.NewServer(mux)
+	return f
+}
+
+// Close shuts down the server of f.
+func (f *Fake{{ $serv }}) Close() {
+	f.Server.Close()
+}
+
+// Client returns a {{ $serv }}Service calling f.
+func (f *Fake{{ $serv }}) Client(opts ...grequests.RequestOption) {{ $serv }}Service {
+	c := New{{ $serv }}Service(opts...).(*{{ unexport $serv }}Service)
+	c.addr = f.Server.URL
+	return c
+}
+{{ range .ServerMethods }}
+// {{ .MethName }}Requests reports the requests {{ .MethName }} received, in order.
+func (f *Fake{{ $serv }}) {{ .MethName }}Requests() []*{{ .ReqTyp }} {
+	var ins []*{{ .ReqTyp }}
+	for _, in := range f.calls.get({{ printf "%q" .MethName }}) {
+		ins = append(ins, in.(*{{ .ReqTyp }}))
+	}
+	return ins
+}
+{{ end }}
+// fake{{ $serv }}Server serves the routes of a Fake{{ $serv }}. It's a type of its
+// own so the methods of {{ $serv }}HTTPServer can't clash with the fake's.
+type fake{{ $serv }}Server struct {
+	f *Fake{{ $serv }}
+}
+{{ range .ServerMethods }}
+func (s fake{{ $serv }}Server) {{ .MethName }}(ctx context.Context, in *{{ .ReqTyp }}) (*{{ .ResTyp }}, error) {
+	s.f.calls.record({{ printf "%q" .MethName }}, in)
+	if s.f.{{ .MethName }}Func == nil {
+		return nil, goapiUnimplemented({{ printf "%q" (printf "Fake%s.%sFunc" $serv .MethName) }})
+	}
+	return s.f.{{ .MethName }}Func(ctx, in)
+}
+{{ end }}
+{{- end -}}
+`
+
+const runtimeFileName = "goapi_runtime.api.go"
+
+var runtimeTmpl = `// Code generated by protoc-gen-go_api(github.com/open-api-go/protoc-gen-go_api version={{ .Version }}). DO NOT EDIT.
+
+package {{ .GoPackage }}
+
+import (
+	errors "errors"
+	fmt "fmt"
+	http "net/http"
+	strings "strings"
+	grequests "github.com/open-api-go/grequests"
+	protojson "google.golang.org/protobuf/encoding/protojson"
+	codepb "google.golang.org/genproto/googleapis/rpc/code"
+	context "context"
+	bytes "bytes"
+	base64 "encoding/base64"
+	io "io"
+	multipart "mime/multipart"
+	url "net/url"
+	textproto "net/textproto"
+	sort "sort"
+	time "time"
+{{- if .Server }}
+	json "encoding/json"
+	regexp "regexp"
+	strconv "strconv"
+	sync "sync"
+	proto "google.golang.org/protobuf/proto"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+{{- end }}
+{{- range .Backends }}{{ if .Import.Path }}
+	{{ .Import.Name }} "{{ .Import.Path }}"
+{{- end }}{{ end }}
+{{- range .Imports }}
+	{{ .Name }} "{{ .Path | html }}"
+{{- end }}
+)
+
+// ClientOption configures a client made by New<Service>Client.
+type ClientOption func(*goapiClientConfig)
+
+type goapiClientConfig struct {
+	baseURL     string
+	requestOpts []grequests.RequestOption
+
+	// credentials of the methods with a (goapi.method).auth option
+	bearerToken func(ctx context.Context) (string, error)
+	basicUser   string
+	basicPass   string
+	apiKey      string
+}
+
+// WithBaseURL sends the requests to baseURL, such as "http://localhost:8080"
+// or "https://example.com/api", instead of the default URL of the service.
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *goapiClientConfig) {
+		c.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithEndpoint sends the requests to https://host, host being
+// a name with an optional port, e.g. "api.example.com:8443".
+func WithEndpoint(host string) ClientOption {
+	return WithBaseURL("https://" + host)
+}
+
+// WithRequestOptions applies opts to every request of the client.
+func WithRequestOptions(opts ...grequests.RequestOption) ClientOption {
+	return func(c *goapiClientConfig) {
+		c.requestOpts = append(c.requestOpts, opts...)
+	}
+}
+
+// WithBearerToken authenticates the methods with SCHEME_BEARER auth
+// by an "Authorization: Bearer" header, token being called for every
+// request so it can be refreshed.
+func WithBearerToken(token func(ctx context.Context) (string, error)) ClientOption {
+	return func(c *goapiClientConfig) {
+		c.bearerToken = token
+	}
+}
+
+// WithBasicAuth authenticates the methods with SCHEME_BASIC auth.
+func WithBasicAuth(user, password string) ClientOption {
+	return func(c *goapiClientConfig) {
+		c.basicUser = user
+		c.basicPass = password
+	}
+}
+
+// WithAPIKey authenticates the methods with SCHEME_API_KEY auth, sending
+// key in the header or the query parameter their option names.
+func WithAPIKey(key string) ClientOption {
+	return func(c *goapiClientConfig) {
+		c.apiKey = key
+	}
+}
+
+// authenticate reports rawURL and the request options authenticating
+// a request by scheme, as set by (goapi.method).auth. Without the
+// credentials of scheme the request is sent as is.
+func (c *goapiClientConfig) authenticate(ctx context.Context, rawURL, scheme, name string, inQuery bool) (string, []grequests.RequestOption, error) {
+	if c == nil {
+		return rawURL, nil, nil
+	}
+	switch scheme {
+	case "bearer":
+		if c.bearerToken == nil {
+			return rawURL, nil, nil
+		}
+		token, err := c.bearerToken(ctx)
+		if err != nil {
+			return "", nil, err
+		}
+		return rawURL, []grequests.RequestOption{grequests.AddHeaders(map[string]string{"Authorization": "Bearer " + token})}, nil
+	case "basic":
+		if c.basicUser == "" && c.basicPass == "" {
+			return rawURL, nil, nil
+		}
+		cred := base64.StdEncoding.EncodeToString([]byte(c.basicUser + ":" + c.basicPass))
+		return rawURL, []grequests.RequestOption{grequests.AddHeaders(map[string]string{"Authorization": "Basic " + cred})}, nil
+	case "api_key":
+		if c.apiKey == "" {
+			return rawURL, nil, nil
+		}
+		if !inQuery {
+			return rawURL, []grequests.RequestOption{grequests.AddHeaders(map[string]string{name: c.apiKey})}, nil
+		}
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return "", nil, err
+		}
+		q := u.Query()
+		q.Set(name, c.apiKey)
+		u.RawQuery = q.Encode()
+		return u.String(), nil, nil
+	}
+	return rawURL, nil, nil
+}
+
+// goapiFilePart is a file part of a multipart/form-data body.
+type goapiFilePart struct {
+	field       string
+	filename    string
+	contentType string
+	content     []byte
+}
+
+var goapiQuoteEscaper = strings.NewReplacer("\\", "\\\\", "\"", "\\\"")
+
+// goapiMultipart encodes the form fields, in sorted order, and the files as
+// a multipart/form-data body, reporting it and its content type, boundary
+// included.
+func goapiMultipart(fields url.Values, files []goapiFilePart) (io.Reader, string, error) {
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range fields[k] {
+			if err := w.WriteField(k, v); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+	for _, f := range files {
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf("form-data; name=\"%s\"; filename=\"%s\"",
+			goapiQuoteEscaper.Replace(f.field), goapiQuoteEscaper.Replace(f.filename)))
+		h.Set("Content-Type", f.contentType)
+		part, err := w.CreatePart(h)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := part.Write(f.content); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return &b, w.FormDataContentType(), nil
+}
+
+// APIError is returned by the generated clients for non-2xx responses.
+type APIError struct {
+	StatusCode int         // HTTP status code
+	Header     http.Header // response header
+	Body       []byte      // raw response body
+	// Detail is Body decoded as {{ .ErrTyp }}, nil if it isn't one.
+	Detail *{{ .ErrTyp }}
+}
+
+func (e *APIError) Error() string {
+	if d, ok := interface{}(e.Detail).(interface{ GetMessage() string }); ok && d.GetMessage() != "" {
+		return fmt.Sprintf("goapi: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), d.GetMessage())
+	}
+	return fmt.Sprintf("goapi: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Code reports the gRPC code of the error. It's the code carried by Detail
+// if any, otherwise the one mapped from StatusCode.
+func (e *APIError) Code() codepb.Code {
+	if d, ok := interface{}(e.Detail).(interface{ GetCode() int32 }); ok && d.GetCode() != 0 {
+		return codepb.Code(d.GetCode())
+	}
+	return goapiHTTPCode(e.StatusCode)
+}
+
+// ErrorCode reports the gRPC code of err: OK for nil,
+// the APIError code if err wraps one, UNKNOWN otherwise.
+func ErrorCode(err error) codepb.Code {
+	if err == nil {
+		return codepb.Code_OK
+	}
+	var e *APIError
+	if errors.As(err, &e) {
+		return e.Code()
+	}
+	return codepb.Code_UNKNOWN
+}
+
+// IsInvalidArgument reports whether err is an APIError with code INVALID_ARGUMENT.
+func IsInvalidArgument(err error) bool { return ErrorCode(err) == codepb.Code_INVALID_ARGUMENT }
+
+// IsFailedPrecondition reports whether err is an APIError with code FAILED_PRECONDITION.
+func IsFailedPrecondition(err error) bool { return ErrorCode(err) == codepb.Code_FAILED_PRECONDITION }
+
+// IsUnauthenticated reports whether err is an APIError with code UNAUTHENTICATED.
+func IsUnauthenticated(err error) bool { return ErrorCode(err) == codepb.Code_UNAUTHENTICATED }
+
+// IsPermissionDenied reports whether err is an APIError with code PERMISSION_DENIED.
+func IsPermissionDenied(err error) bool { return ErrorCode(err) == codepb.Code_PERMISSION_DENIED }
+
+// IsNotFound reports whether err is an APIError with code NOT_FOUND.
+func IsNotFound(err error) bool { return ErrorCode(err) == codepb.Code_NOT_FOUND }
+
+// IsAlreadyExists reports whether err is an APIError with code ALREADY_EXISTS.
+func IsAlreadyExists(err error) bool { return ErrorCode(err) == codepb.Code_ALREADY_EXISTS }
+
+// IsAborted reports whether err is an APIError with code ABORTED.
+func IsAborted(err error) bool { return ErrorCode(err) == codepb.Code_ABORTED }
+
+// IsResourceExhausted reports whether err is an APIError with code RESOURCE_EXHAUSTED.
+func IsResourceExhausted(err error) bool { return ErrorCode(err) == codepb.Code_RESOURCE_EXHAUSTED }
+
+// IsUnimplemented reports whether err is an APIError with code UNIMPLEMENTED.
+func IsUnimplemented(err error) bool { return ErrorCode(err) == codepb.Code_UNIMPLEMENTED }
+
+// IsInternal reports whether err is an APIError with code INTERNAL.
+func IsInternal(err error) bool { return ErrorCode(err) == codepb.Code_INTERNAL }
+
+// IsUnavailable reports whether err is an APIError with code UNAVAILABLE.
+func IsUnavailable(err error) bool { return ErrorCode(err) == codepb.Code_UNAVAILABLE }
+
+// IsDeadlineExceeded reports whether err is an APIError with code DEADLINE_EXCEEDED.
+func IsDeadlineExceeded(err error) bool { return ErrorCode(err) == codepb.Code_DEADLINE_EXCEEDED }
+
+// goapiHTTPCode maps an HTTP status code to a gRPC code,
+// following https://github.com/googleapis/googleapis/blob/master/google/rpc/code.proto
+func goapiHTTPCode(status int) codepb.Code {
+	switch {
+	case status/100 == 2:
+		return codepb.Code_OK
+	case status == http.StatusBadRequest:
+		return codepb.Code_INVALID_ARGUMENT
+	case status == http.StatusUnauthorized:
+		return codepb.Code_UNAUTHENTICATED
+	case status == http.StatusForbidden:
+		return codepb.Code_PERMISSION_DENIED
+	case status == http.StatusNotFound:
+		return codepb.Code_NOT_FOUND
+	case status == http.StatusConflict:
+		return codepb.Code_ABORTED
+	case status == http.StatusPreconditionFailed:
+		return codepb.Code_FAILED_PRECONDITION
+	case status == http.StatusRequestedRangeNotSatisfiable:
+		return codepb.Code_OUT_OF_RANGE
+	case status == http.StatusTooManyRequests:
+		return codepb.Code_RESOURCE_EXHAUSTED
+	case status == 499:
+		return codepb.Code_CANCELLED
+	case status == http.StatusNotImplemented:
+		return codepb.Code_UNIMPLEMENTED
+	case status == http.StatusServiceUnavailable:
+		return codepb.Code_UNAVAILABLE
+	case status == http.StatusGatewayTimeout:
+		return codepb.Code_DEADLINE_EXCEEDED
+	case status/100 == 4:
+		return codepb.Code_FAILED_PRECONDITION
+	case status >= 500:
+		return codepb.Code_INTERNAL
+	}
+	return codepb.Code_UNKNOWN
+}
+
+// goapiCheckResponse returns an *APIError if resp isn't a 2xx response.
+func goapiCheckResponse(resp *grequests.Response) error {
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	e := &APIError{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       resp.Bytes(),
+	}
+	detail := new({{ .ErrTyp }})
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(e.Body, detail); err == nil {
+		e.Detail = detail
+	}
+	return e
+}
+
+// goapiWithTimeout sends a request with do, limiting ctx to the timeout d
+// when it has no deadline. The timeout covers reading the response body too,
+// and is released once the body is closed.
+func goapiWithTimeout(ctx context.Context, d time.Duration, do func(context.Context) (*grequests.Response, error)) (*grequests.Response, error) {
+	if _, ok := ctx.Deadline(); ok {
+		return do(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, d)
+	resp, err := do(ctx)
+	if err != nil || resp == nil || resp.RawResponse == nil || resp.RawResponse.Body == nil {
+		cancel()
+		return resp, err
+	}
+	resp.RawResponse.Body = &goapiCancelBody{ReadCloser: resp.RawResponse.Body, cancel: cancel}
+	return resp, nil
+}
+
+// goapiCancelBody cancels the context of a request once its body is closed.
+type goapiCancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *goapiCancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// goapiEscape percent-encodes s as the value of a path variable: every byte
+// but [-_.~0-9a-zA-Z] is encoded, and so is '/' unless multi is set for
+// variables matching several segments, see google.api.HttpRule.
+func goapiEscape(s string, multi bool) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', multi && c == '/':
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&15])
+		}
+	}
+	return b.String()
+}
+
+// goapiQueryValue formats the well-known type m as a query or form value:
+// its JSON form, unquoted if that's a string, e.g. "2017-01-15T01:30:15Z"
+// for a Timestamp or "1.5s" for a Duration.
+func goapiQueryValue(m proto.Message) (string, error) {
+	bs, err := protojson.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	var s string
+	if err := json.Unmarshal(bs, &s); err != nil {
+		// Not a string in JSON, e.g. an Int64Value.
+		return string(bs), nil
+	}
+	return s, nil
+}
+{{- if .Server }}
+
+// goapiRoute is a route of a generated server.
+type goapiRoute struct {
+	method     string         // HTTP method
+	path       string         // google.api.http path template
+	prefix     string         // literal prefix of path, as a ServeMux pattern
+	pattern    *regexp.Regexp // matches the escaped path, a group per variable
+	vars       []string       // field paths of the variables
+	verbSuffix bool           // path ends with a custom verb, e.g. :cancel
+	// segments is path for routers with path parameters: literal segments,
+	// "*" for any one segment and "**" for the rest of the path.
+	segments []string
+	handle   func(w http.ResponseWriter, r *http.Request, vars map[string]string)
+}
+
+// goapiDispatchers holds the dispatchers of a registration by router path,
+// so routes sharing a router path share a dispatcher instead of panicking.
+type goapiDispatchers map[string]*goapiDispatcher
+
+// get returns the dispatcher of key, creating it and calling register with
+// it on first use.
+func (ds goapiDispatchers) get(key string, register func(d *goapiDispatcher)) *goapiDispatcher {
+	d := ds[key]
+	if d == nil {
+		d = new(goapiDispatcher)
+		ds[key] = d
+		register(d)
+	}
+	return d
+}
+
+// goapiRouterPath formats the segments of rt as a router path, naming the
+// parameter of the i-th segment with param.
+func goapiRouterPath(rt *goapiRoute, param func(i int, catchAll bool) string) string {
+	var b strings.Builder
+	for i, seg := range rt.segments {
+		b.WriteByte('/')
+		switch seg {
+		case "*", "**":
+			b.WriteString(param(i, seg == "**"))
+		default:
+			b.WriteString(seg)
+		}
+	}
+	return b.String()
+}
+
+
+// goapiRegister registers routes on mux, by literal prefix. A prefix
+// already served by a dispatcher, of another service, gets the routes added.
+func goapiRegister(mux *http.ServeMux, routes []*goapiRoute) {
+	for _, rt := range routes {
+		h, pattern := mux.Handler(&http.Request{Method: http.MethodGet, URL: &url.URL{Path: rt.prefix}})
+		d, ok := h.(*goapiDispatcher)
+		if !ok || pattern != rt.prefix {
+			d = new(goapiDispatcher)
+			mux.Handle(rt.prefix, d)
+		}
+		d.add(rt)
+	}
+}
+{{- range .Backends }}{{ if .Runtime }}
+{{ .Runtime }}
+{{- end }}{{ end }}
+
+// goapiDispatcher serves the routes registered under a prefix,
+// trying them in order.
+type goapiDispatcher struct {
+	mu     sync.RWMutex
+	routes []*goapiRoute
+}
+
+func (d *goapiDispatcher) add(rt *goapiRoute) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	i := 0
+	for i < len(d.routes) && d.routes[i].rank() <= rt.rank() {
+		i++
+	}
+	d.routes = append(d.routes[:i], append([]*goapiRoute{rt}, d.routes[i:]...)...)
+}
+
+func (d *goapiDispatcher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.serve(w, r, r.URL.EscapedPath())
+}
+
+// serveGroup serves r routed by a router group: full is the pattern the
+// router matched, p the one registered for the route, and the segments
+// full has more than p belong to the group and aren't matched.
+func (d *goapiDispatcher) serveGroup(w http.ResponseWriter, r *http.Request, full, p string) {
+	path := r.URL.EscapedPath()
+	for n := strings.Count(full, "/") - strings.Count(p, "/"); n > 0; n-- {
+		i := strings.IndexByte(path[1:], '/')
+		if i < 0 {
+			break
+		}
+		path = path[i+1:]
+	}
+	d.serve(w, r, path)
+}
+
+// serve serves r, whose escaped path is path.
+func (d *goapiDispatcher) serve(w http.ResponseWriter, r *http.Request, path string) {
+	d.mu.RLock()
+	routes := d.routes
+	d.mu.RUnlock()
+
+	var allow []string
+	for _, rt := range routes {
+		vars, ok, err := rt.match(path)
+		if err != nil {
+			goapiWriteError(w, goapiBadRequest(err))
+			return
+		}
+		if !ok {
+			continue
+		}
+		if rt.method != r.Method {
+			allow = append(allow, rt.method)
+			continue
+		}
+		rt.handle(w, r, vars)
+		return
+	}
+	if len(allow) > 0 {
+		w.Header().Set("Allow", strings.Join(allow, ", "))
+		goapiWriteError(w, goapiStatusError(http.StatusMethodNotAllowed, codepb.Code_UNIMPLEMENTED, http.StatusText(http.StatusMethodNotAllowed)))
+		return
+	}
+	goapiWriteError(w, goapiStatusError(http.StatusNotFound, codepb.Code_NOT_FOUND, http.StatusText(http.StatusNotFound)))
+}
+
+// catchAll reports whether the path of rt ends with a ** variable.
+func (rt *goapiRoute) catchAll() bool {
+	return len(rt.segments) > 0 && rt.segments[len(rt.segments)-1] == "**"
+}
+
+// rank orders the routes of a dispatcher, which tries the lowest first.
+// Routes with a catch-all go last, and routes ending with a custom verb
+// first among the others: "/v1/{path=**}" would otherwise shadow
+// "/v1/items/{id}", and "/v1/{name}" shadow "/v1/{name}:cancel".
+func (rt *goapiRoute) rank() int {
+	r := 0
+	if rt.catchAll() {
+		r += 2
+	}
+	if !rt.verbSuffix {
+		r++
+	}
+	return r
+}
+
+// match reports whether the escaped path p matches rt,
+// and the unescaped values of the variables by field path.
+func (rt *goapiRoute) match(p string) (map[string]string, bool, error) {
+	m := rt.pattern.FindStringSubmatch(p)
+	if m == nil {
+		return nil, false, nil
+	}
+	vars := make(map[string]string, len(rt.vars))
+	for i, name := range rt.vars {
+		v, err := url.PathUnescape(m[i+1])
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: %v", name, err)
+		}
+		vars[name] = v
+	}
+	return vars, true, nil
+}
+
+// goapiErrUnknownField is returned when a field path doesn't name a field.
+// Unknown query and form parameters are ignored.
+var goapiErrUnknownField = errors.New("unknown field")
+
+// goapiFileField names the fields getting the file name and the content
+// type of a file part of a multipart body, see (goapi.field).file.
+type goapiFileField struct {
+	filenameField    string
+	contentTypeField string
+}
+
+// goapiBind fills in from r following the HttpRule: the body first, then
+// the path variables, then the query parameters for every field bound to
+// neither. body is the HttpRule.body field path, format its encoding and
+// files the file parts of a multipart body by name.
+func goapiBind(r *http.Request, in proto.Message, body, format string, vars map[string]string, files map[string]goapiFileField) error {
+	if body != "" {
+		if err := goapiBindBody(r, in, body, format, files); err != nil {
+			return goapiBadRequest(err)
+		}
+	}
+	msg := in.ProtoReflect()
+	for p, v := range vars {
+		if err := goapiSetField(msg, p, []string{v}); err != nil {
+			return goapiBadRequest(err)
+		}
+	}
+	if body == "*" {
+		return nil
+	}
+	for k, vs := range r.URL.Query() {
+{{- if eq .QueryStyle "brackets" }}
+		k = strings.TrimSuffix(k, "[]")
+{{- end }}
+		if _, ok := vars[k]; ok || body != "" && (k == body || strings.HasPrefix(k, body+".")) {
+			continue
+		}
+{{- if eq .QueryStyle "comma" }}
+		// The values of repeated fields are joined by commas.
+		if fds, err := goapiFieldPath(msg.Descriptor(), k); err == nil && fds[len(fds)-1].IsList() {
+			vs = strings.Split(strings.Join(vs, ","), ",")
+		}
+{{- end }}
+		if err := goapiSetField(msg, k, vs); err != nil && !errors.Is(err, goapiErrUnknownField) {
+			return goapiBadRequest(err)
+		}
+	}
+	return nil
+}
+
+// goapiBindBody decodes the body of r into the field body of in, or into
+// in itself if body is "*".
+func goapiBindBody(r *http.Request, in proto.Message, body, format string, files map[string]goapiFileField) error {
+	if format == "form" || format == "multi" {
+		if err := r.ParseMultipartForm(32 << 20); err != nil && err != http.ErrNotMultipart {
+			return err
+		}
+		prefix := ""
+		if body != "*" {
+			prefix = body + "."
+		}
+		msg := in.ProtoReflect()
+		md := msg.Descriptor()
+		if body != "*" {
+			fds, err := goapiFieldPath(md, body)
+			if err != nil {
+				return err
+			}
+			md = fds[len(fds)-1].Message()
+		}
+		for k, vs := range r.PostForm {
+			if err := goapiSetField(msg, prefix+goapiFormPath(md, k), vs); err != nil && !errors.Is(err, goapiErrUnknownField) {
+				return err
+			}
+		}
+		if r.MultipartForm == nil {
+			return nil
+		}
+		for k, fhs := range r.MultipartForm.File {
+			for _, fh := range fhs {
+				if err := goapiBindFile(msg, prefix+goapiFormPath(md, k), fh); errors.Is(err, goapiErrUnknownField) {
+					continue
+				} else if err != nil {
+					return err
+				}
+				set := func(field, value string) error {
+					if field == "" || value == "" {
+						return nil
+					}
+					return goapiSetField(msg, prefix+field, []string{value})
+				}
+				if err := set(files[k].filenameField, fh.Filename); err != nil {
+					return err
+				}
+				if err := set(files[k].contentTypeField, fh.Header.Get("Content-Type")); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	bs, err := io.ReadAll(r.Body)
+	if err != nil || len(bs) == 0 {
+		return err
+	}
+	if body == "*" {
+		return protojson.Unmarshal(bs, in)
+	}
+	fds, err := goapiFieldPath(in.ProtoReflect().Descriptor(), body)
+	if err != nil {
+		return err
+	}
+	msg := in.ProtoReflect()
+	for _, fd := range fds[:len(fds)-1] {
+		msg = msg.Mutable(fd).Message()
+	}
+	// Decode {"<field>":<body>} into the parent message,
+	// protojson has no other way to decode a single field.
+	key := strconv.Quote(fds[len(fds)-1].JSONName())
+	bs = append(append([]byte("{"+key+":"), bs...), '}')
+	return protojson.Unmarshal(bs, msg.Interface())
+}
+
+// goapiFormSeparator joins the names of nested fields in the keys of form
+// and multipart bodies, see the form_separator parameter.
+const goapiFormSeparator = {{ printf "%q" .FormSeparator }}
+
+// goapiFormPath reports the field path of md the key k of a form body
+// stands for. The names in k are joined by goapiFormSeparator, which may
+// be in the field names too. k is returned as is if no field matches.
+func goapiFormPath(md protoreflect.MessageDescriptor, k string) string {
+	if goapiFormSeparator == "." || md == nil {
+		return k
+	}
+	if p, ok := goapiSplitFormKey(md, strings.Split(k, goapiFormSeparator)); ok {
+		return p
+	}
+	return k
+}
+
+// goapiSplitFormKey reports the field path of md made of names, the field
+// names being one or more of them joined by goapiFormSeparator.
+func goapiSplitFormKey(md protoreflect.MessageDescriptor, names []string) (string, bool) {
+	for n := len(names); n > 0; n-- {
+		name := strings.Join(names[:n], goapiFormSeparator)
+		fd := md.Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			fd = md.Fields().ByJSONName(name)
+		}
+		switch {
+		case fd == nil:
+		case n == len(names):
+			return name, true
+		case fd.Message() != nil && !fd.IsList() && !fd.IsMap():
+			if p, ok := goapiSplitFormKey(fd.Message(), names[n:]); ok {
+				return name + "." + p, true
+			}
+		}
+	}
+	return "", false
+}
+
+// goapiBindFile sets the bytes or string field p of msg to the content of
+// the file part fh, appending it to a repeated field.
+func goapiBindFile(msg protoreflect.Message, p string, fh *multipart.FileHeader) error {
+	fds, err := goapiFieldPath(msg.Descriptor(), p)
+	if err != nil {
+		return err
+	}
+	for _, fd := range fds[:len(fds)-1] {
+		msg = msg.Mutable(fd).Message()
+	}
+	fd := fds[len(fds)-1]
+	f, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	var v protoreflect.Value
+	switch fd.Kind() {
+	case protoreflect.BytesKind:
+		v = protoreflect.ValueOfBytes(content)
+	case protoreflect.StringKind:
+		v = protoreflect.ValueOfString(string(content))
+	default:
+		return fmt.Errorf("%s: a file part must be a bytes or string field", p)
+	}
+	if fd.IsList() {
+		msg.Mutable(fd).List().Append(v)
+	} else {
+		msg.Set(fd, v)
+	}
+	return nil
+}
+
+// goapiFieldPath resolves the field path p, e.g. "shelf.name", from md.
+// Every name but the last must be a singular message field. Names are
+// proto names or JSON names.
+func goapiFieldPath(md protoreflect.MessageDescriptor, p string) ([]protoreflect.FieldDescriptor, error) {
+	names := strings.Split(p, ".")
+	fds := make([]protoreflect.FieldDescriptor, 0, len(names))
+	for i, name := range names {
+		if md == nil {
+			return nil, fmt.Errorf("%s: %s is not a message", p, names[i-1])
+		}
+		fd := md.Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			fd = md.Fields().ByJSONName(name)
+		}
+		if fd == nil {
+			return nil, fmt.Errorf("%s: %w", p, goapiErrUnknownField)
+		}
+		if i < len(names)-1 && (fd.IsList() || fd.IsMap()) {
+			return nil, fmt.Errorf("%s: %s is repeated", p, name)
+		}
+		fds = append(fds, fd)
+		md = fd.Message()
+	}
+	return fds, nil
+}
+
+// goapiSetField sets the field p of msg from its string values, appending
+// them all to a repeated field, keeping the last one otherwise.
+func goapiSetField(msg protoreflect.Message, p string, values []string) error {
+	fds, err := goapiFieldPath(msg.Descriptor(), p)
+	if err != nil {
+		return err
+	}
+	for _, fd := range fds[:len(fds)-1] {
+		msg = msg.Mutable(fd).Message()
+	}
+	fd := fds[len(fds)-1]
+	switch {
+	case fd.IsMap():
+		return fmt.Errorf("%s: map fields can't be bound from the URL", p)
+	case fd.IsList():
+		list := msg.Mutable(fd).List()
+		for _, s := range values {
+			v, err := goapiParseValue(fd, s, list.NewElement())
+			if err != nil {
+				return fmt.Errorf("%s: %v", p, err)
+			}
+			list.Append(v)
+		}
+	case len(values) > 0:
+		v, err := goapiParseValue(fd, values[len(values)-1], msg.NewField(fd))
+		if err != nil {
+			return fmt.Errorf("%s: %v", p, err)
+		}
+		msg.Set(fd, v)
+	}
+	return nil
+}
+
+// goapiParseValue parses s as a value of fd. zero is a new value of fd,
+// used for messages, which must be well-known types given in JSON form
+// such as a Timestamp.
+func goapiParseValue(fd protoreflect.FieldDescriptor, s string, zero protoreflect.Value) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		v, err := strconv.ParseBool(s)
+		return protoreflect.ValueOfBool(v), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		v, err := strconv.ParseInt(s, 10, 32)
+		return protoreflect.ValueOfInt32(int32(v)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		v, err := strconv.ParseInt(s, 10, 64)
+		return protoreflect.ValueOfInt64(v), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		v, err := strconv.ParseUint(s, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(v)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		v, err := strconv.ParseUint(s, 10, 64)
+		return protoreflect.ValueOfUint64(v), err
+	case protoreflect.FloatKind:
+		v, err := strconv.ParseFloat(s, 32)
+		return protoreflect.ValueOfFloat32(float32(v)), err
+	case protoreflect.DoubleKind:
+		v, err := strconv.ParseFloat(s, 64)
+		return protoreflect.ValueOfFloat64(v), err
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(s), nil
+	case protoreflect.BytesKind:
+		v, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			v, err = base64.URLEncoding.DecodeString(s)
+		}
+		return protoreflect.ValueOfBytes(v), err
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(s)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		v, err := strconv.ParseInt(s, 10, 32)
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(v)), err
+	}
+	m := zero.Message().Interface()
+	if err := protojson.Unmarshal([]byte(strconv.Quote(s)), m); err != nil {
+		// Not a string in JSON, e.g. an Int64Value.
+		if err := protojson.Unmarshal([]byte(s), m); err != nil {
+			return protoreflect.Value{}, err
+		}
+	}
+	return zero, nil
+}
+
+// goapiWriteResponse writes out in JSON, or its field responseBody only
+// if set, see HttpRule.response_body.
+func goapiWriteResponse(w http.ResponseWriter, out proto.Message, responseBody string) {
+	opts := protojson.MarshalOptions{}
+	if responseBody != "" {
+		// The field must be there to pick it even if it's unset.
+		opts.EmitUnpopulated = true
+	}
+	bs, err := opts.Marshal(out)
+	if err != nil {
+		goapiWriteError(w, err)
+		return
+	}
+	if responseBody != "" {
+		fd := out.ProtoReflect().Descriptor().Fields().ByName(protoreflect.Name(responseBody))
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(bs, &fields); err != nil || fd == nil {
+			goapiWriteError(w, fmt.Errorf("response_body %q: %v", responseBody, err))
+			return
+		}
+		bs = fields[fd.JSONName()]
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(bs)
+}
+
+// goapiWriteError writes err as the response. An *APIError is written as
+// is, so implementations choose the status by returning one, other errors
+// are a 500 carrying a google.rpc.Status with code UNKNOWN.
+func goapiWriteError(w http.ResponseWriter, err error) {
+	var e *APIError
+	if !errors.As(err, &e) {
+		e = goapiStatusError(http.StatusInternalServerError, codepb.Code_UNKNOWN, err.Error())
+	}
+	status := e.StatusCode
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	body := e.Body
+	if len(body) == 0 {
+		body = goapiStatusError(status, e.Code(), e.Error()).Body
+	}
+	for k, vs := range e.Header {
+		w.Header()[k] = vs
+	}
+	w.Header().Del("Content-Length")
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// goapiBadRequest is the error of a request that can't be bound.
+func goapiBadRequest(err error) error {
+	var e *APIError
+	if errors.As(err, &e) {
+		return e
+	}
+	return goapiStatusError(http.StatusBadRequest, codepb.Code_INVALID_ARGUMENT, err.Error())
+}
+
+// goapiStatusError builds an APIError whose body is a google.rpc.Status in JSON.
+func goapiStatusError(status int, code codepb.Code, msg string) *APIError {
+	body, _ := json.Marshal(map[string]interface{}{"code": int32(code), "message": msg})
+	return &APIError{StatusCode: status, Body: body}
+}
+{{- end }}
+{{- if .Fake }}
+
+// goapiFakeCalls records the requests received by a fake, by method.
+type goapiFakeCalls struct {
+	mu    sync.Mutex
+	calls map[string][]proto.Message
+}
+
+func (c *goapiFakeCalls) record(meth string, in proto.Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.calls == nil {
+		c.calls = map[string][]proto.Message{}
+	}
+	c.calls[meth] = append(c.calls[meth], proto.Clone(in))
+}
+
+func (c *goapiFakeCalls) get(meth string) []proto.Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]proto.Message(nil), c.calls[meth]...)
+}
+
+// goapiUnimplemented is the error of a fake method without handler.
+func goapiUnimplemented(handler string) error {
+	return goapiStatusError(http.StatusNotImplemented, codepb.Code_UNIMPLEMENTED, handler+" is not set")
+}
+{{- end }}
+`
+
 var bodyFormTmpl = `	// 处理form的body
-	bodyForms := make(map[string]string)
+	bodyForms := url.Values{}
 	{{ .BodyForm | html }}
 	if len(bodyForms) > 0 {
-		bs := make([]string, 0, len(bodyForms))	
-		for k, v := range bodyForms {
-			bs = append(bs, fmt.Sprintf("%s=%s", k, v))
-		}
 		headers := map[string]string {
 			"Content-Type": "application/x-www-form-urlencoded",
 		}
-		opts = append(opts, grequests.RequestBody(strings.NewReader(strings.Join(bs, "&"))), grequests.AddHeaders(headers))
+		// Encode sorts the keys, so the body is the same for the same request.
+		opts = append(opts, grequests.RequestBody(strings.NewReader(bodyForms.Encode())), grequests.AddHeaders(headers))
 	}
 `
 
 var queryStringTmpl = `	// 处理query string
-	params := make(map[string]string)
+	params := url.Values{}
 	{{ .QueryString | html }}
 	if len(params) > 0 {
-		opts = append(opts, grequests.Params(params))
+		rawURL += "?" + params.Encode()
 	}
 `
 
 var bodyMultiPartTmpl = `	// 处理multipart的body
-	forms := make(map[string]string)
+	forms := url.Values{}
 	{{ .BodyForm | html }}
-	if len(forms) > 0 {
-		var bs string
-		for k, v := range forms {
-			bs = fmt.Sprintf("%s&%s=%s", bs, k, v)
-		}
-		headers := map[string]string {
-			"Content-Type": "multipart/form-data",
-		}
-		opts = append(opts, grequests.RequestBody(strings.NewReader(bs)), grequests.AddHeaders(headers))
+	var files []goapiFilePart
+	{{ .Files | html }}
+	multipartBody, contentType, err := goapiMultipart(forms, files)
+	if err != nil {
+		return nil, err
 	}
+	headers := map[string]string {
+		"Content-Type": contentType,
+	}
+	opts = append(opts, grequests.RequestBody(multipartBody), grequests.AddHeaders(headers))
 `
 
 func getGoapiContent(data *FileData) (string, error) {
@@ -107,6 +1466,67 @@ func getGoapiContent(data *FileData) (string, error) {
 	return bs.String(), nil
 }
 
+func getRuntimeContent(data *RuntimeData) (string, error) {
+	// Plain text/template: the runtime code has no html escaping to opt out of.
+	cm, err := texttemplate.New("runtime_tmpl").Funcs(fn).Parse(runtimeTmpl)
+	if err != nil {
+		log.Println("parse runtime template err: ", err)
+		return "", err
+	}
+	bs := new(bytes.Buffer)
+	err = cm.Execute(bs, data)
+	if err != nil {
+		log.Println("execute runtime template err: ", err)
+		return "", err
+	}
+	return bs.String(), nil
+}
+
+func getServerContent(data *FileData) (string, error) {
+	cm, err := texttemplate.New("server_tmpl").Funcs(fn).Parse(serverTmpl)
+	if err != nil {
+		log.Println("parse server template err: ", err)
+		return "", err
+	}
+	bs := new(bytes.Buffer)
+	err = cm.Execute(bs, data)
+	if err != nil {
+		log.Println("execute server template err: ", err)
+		return "", err
+	}
+	return bs.String(), nil
+}
+
+func getMockContent(data *FileData) (string, error) {
+	cm, err := texttemplate.New("mock_tmpl").Funcs(fn).Parse(mockTmpl)
+	if err != nil {
+		log.Println("parse mock template err: ", err)
+		return "", err
+	}
+	bs := new(bytes.Buffer)
+	err = cm.Execute(bs, data)
+	if err != nil {
+		log.Println("execute mock template err: ", err)
+		return "", err
+	}
+	return bs.String(), nil
+}
+
+func getFakeContent(data *FileData) (string, error) {
+	cm, err := texttemplate.New("fake_tmpl").Funcs(fn).Parse(fakeTmpl)
+	if err != nil {
+		log.Println("parse fake template err: ", err)
+		return "", err
+	}
+	bs := new(bytes.Buffer)
+	err = cm.Execute(bs, data)
+	if err != nil {
+		log.Println("execute fake template err: ", err)
+		return "", err
+	}
+	return bs.String(), nil
+}
+
 func getBodyFormContent(forms string) (string, error) {
 	cm, err := template.New("bodyform_tmpl").Funcs(fn).Parse(bodyFormTmpl)
 	if err != nil {
@@ -141,7 +1561,7 @@ func getQueryStringContent(param string) (string, error) {
 	return bs.String(), nil
 }
 
-func getMultipartContent(forms string) (string, error) {
+func getMultipartContent(forms, files string) (string, error) {
 	cm, err := template.New("multipart_tmpl").Funcs(fn).Parse(bodyMultiPartTmpl)
 	if err != nil {
 		log.Println("parse body multipart template err: ", err)
@@ -150,6 +1570,7 @@ func getMultipartContent(forms string) (string, error) {
 	bs := new(bytes.Buffer)
 	err = cm.Execute(bs, map[string]string{
 		"BodyForm": forms,
+		"Files":    files,
 	})
 	if err != nil {
 		log.Println("execute body multipart template err: ", err)
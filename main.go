@@ -14,7 +14,6 @@ import (
 
 var (
 	showVersion = flag.Bool("version", false, "print the version and exit")
-	omitempty   = flag.Bool("omitempty", true, "omit if google.api is empty")
 )
 
 func main() {
@@ -29,7 +28,7 @@ func main() {
 
 	genResp, err := goapi.Gen(&genReq)
 	if err != nil {
-		genResp.Error = proto.String(err.Error())
+		genResp = &plugin.CodeGeneratorResponse{Error: proto.String(err.Error())}
 	}
 
 	genResp.SupportedFeatures = proto.Uint64(uint64(plugin.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL))
@@ -17,8 +17,11 @@ package pbinfo
 
 import (
 	"fmt"
+	"go/token"
+	"path"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"google.golang.org/protobuf/proto"
@@ -163,10 +166,7 @@ func (in *Info) NameSpec(e ProtoType) (string, ImportSpec, error) {
 		return "", ImportSpec{}, fmt.Errorf("can't determine import path for %v; can't find parent file", eTxt)
 	}
 
-	pkg := fdesc.GetOptions().GetGoPackage()
-	if pkgOverride, ok := in.PkgOverrides[fdesc.GetName()]; ok {
-		pkg = pkgOverride
-	}
+	pkg := in.goPackageOption(fdesc)
 	if pkg == "" {
 		return "", ImportSpec{}, fmt.Errorf("can't determine import path for %v, file %q missing `option go_package`", eTxt, fdesc.GetName())
 	}
@@ -175,21 +175,61 @@ func (in *Info) NameSpec(e ProtoType) (string, ImportSpec, error) {
 		return name, ImportSpec{Path: pkg[:p], Name: appendpb(pkg[p+1:])}, nil
 	}
 
-	for {
-		p := strings.LastIndexByte(pkg, '/')
+	// The import path is pkg as is; only the name skips version elements.
+	for elems := pkg; ; {
+		p := strings.LastIndexByte(elems, '/')
 		if p < 0 {
-			return name, ImportSpec{Path: pkg, Name: appendpb(pkg)}, nil
+			return name, ImportSpec{Path: pkg, Name: appendpb(elems)}, nil
 		}
-		elem := pkg[p+1:]
+		elem := elems[p+1:]
 		if len(elem) >= 2 && elem[0] == 'v' && elem[1] >= '0' && elem[1] <= '9' {
 			// It's a version number; skip so we get a more meaningful name
-			pkg = pkg[:p]
+			elems = elems[:p]
 			continue
 		}
 		return name, ImportSpec{Path: pkg, Name: appendpb(elem)}, nil
 	}
 }
 
+// GoPackage reports the import path and package name of the Go package
+// generated for file f. Unlike NameSpec, the name is the one used in the
+// package clause, derived the same way protoc-gen-go derives it.
+func (in *Info) GoPackage(f *descriptor.FileDescriptorProto) (ImportSpec, error) {
+	pkg := in.goPackageOption(f)
+	if pkg == "" {
+		return ImportSpec{}, fmt.Errorf("can't determine Go package for file %q, missing `option go_package` or M%s=<import path> parameter", f.GetName(), f.GetName())
+	}
+	if p := strings.IndexByte(pkg, ';'); p >= 0 {
+		return ImportSpec{Path: pkg[:p], Name: goSanitized(pkg[p+1:])}, nil
+	}
+	return ImportSpec{Path: pkg, Name: goSanitized(path.Base(pkg))}, nil
+}
+
+// goPackageOption reports the go_package option of f,
+// or its replacement in PkgOverrides.
+func (in *Info) goPackageOption(f *descriptor.FileDescriptorProto) string {
+	if pkgOverride, ok := in.PkgOverrides[f.GetName()]; ok {
+		return pkgOverride
+	}
+	return f.GetOptions().GetGoPackage()
+}
+
+// goSanitized converts s into a valid Go identifier.
+func goSanitized(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, s)
+
+	r, _ := utf8.DecodeRuneInString(s)
+	if token.Lookup(s).IsKeyword() || !unicode.IsLetter(r) {
+		return "_" + s
+	}
+	return s
+}
+
 // ImportSpec reports the ImportSpec for package containing protobuf element e.
 // Deprecated: Use NameSpec instead.
 func (in *Info) ImportSpec(e ProtoType) (ImportSpec, error) {